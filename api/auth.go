@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (model.User, error)
+}
+
+// RouterAuthenticator requires every request to carry a bearer token accepted
+// by the authenticator. Without it, requests are not authenticated and are not
+// restricted to the messages of a user.
+func RouterAuthenticator(authenticator Authenticator) routerOptsFunc {
+	return func(h *handler) {
+		h.authenticator = authenticator
+	}
+}
+
+// authenticate rejects requests without a valid API key or bearer token and
+// puts the authenticated user into the request context.
+func (h *handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get("X-API-Key"); key != "" {
+			h.authenticateAPIKey(w, r, key, next)
+			return
+		}
+
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondJSONStatus(w, &HTTPError{Message: "bearer token is required"}, http.StatusUnauthorized)
+			return
+		}
+
+		user, err := h.authenticator.Authenticate(r.Context(), token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			respondJSONStatus(w, &HTTPError{Message: "invalid token"}, http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(core.ContextWithUser(r.Context(), user)))
+	})
+}
+
+func (h *handler) authenticateAPIKey(w http.ResponseWriter, r *http.Request, key string, next http.Handler) {
+	user, err := h.service.AuthenticateAPIKey(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			respondJSONStatus(w, &HTTPError{Message: "invalid API key"}, http.StatusUnauthorized)
+			return
+		}
+		h.logger.Error("error authenticating api key", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error authenticating API key"}, http.StatusInternalServerError)
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(core.ContextWithUser(r.Context(), user)))
+}
+
+// requireScope rejects requests of users that have not been granted the scope.
+func (h *handler) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, ok := core.UserFromContext(r.Context()); ok && !user.HasScope(scope) {
+				respondJSONStatus(w, &HTTPError{Message: fmt.Sprintf("scope %s is required", scope)}, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireAdmin rejects requests of users that are not admins.
+func (h *handler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := core.UserFromContext(r.Context()); ok && !user.Admin {
+			respondJSONStatus(w, &HTTPError{Message: "admin role is required"}, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,624 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func newAuthenticator() *mock.Authenticator {
+	return &mock.Authenticator{
+		AuthenticateFunc: func(ctx context.Context, token string) (model.User, error) {
+			if token != "token" {
+				return model.User{}, errors.New("invalid token")
+			}
+			return model.User{Name: "user", Scopes: model.Scopes}, nil
+		},
+	}
+}
+
+func newAuthenticatedRequest(t *testing.T, method, url string, body io.Reader) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	return req
+}
+
+func TestHandler_Authenticate(t *testing.T) {
+	t.Run("should return 401 if no bearer token is provided", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusUnauthorized; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if got, want := resp.Header.Get("WWW-Authenticate"), "Bearer"; got != want {
+			t.Errorf("got WWW-Authenticate header %q, want %q", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"bearer token is required\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 401 if the token is invalid", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/messages", testServer.URL), nil)
+		req.Header.Set("Authorization", "Bearer other-token")
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusUnauthorized; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"invalid token\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should put the user into the request context", func(t *testing.T) {
+		var gotUser model.User
+		service := &mock.Service{
+			FetchNewMessagesFunc: func(ctx context.Context, recipientUserName string) ([]model.Message, error) {
+				gotUser, _ = core.UserFromContext(ctx)
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/users/user/messages/new", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if diff := cmp.Diff(model.User{Name: "user", Scopes: model.Scopes}, gotUser); diff != "" {
+			t.Errorf("user mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return 403 if new messages of another user are fetched", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/users/other-user/messages/new", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should return 404 if a message of another user is requested", func(t *testing.T) {
+		service := &mock.Service{
+			GetMessageFunc: func(ctx context.Context, messageID string) (model.Message, error) {
+				return model.Message{ID: messageID, SenderUserName: "other-sender", RecipientUserName: "other-user"}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/messages/message-id", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should only search messages of the user", func(t *testing.T) {
+		gotSearchMessagesCalled := false
+		service := &mock.Service{
+			SearchMessagesFunc: func(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error) {
+				gotSearchMessagesCalled = true
+				wantRecipientUserName := "user"
+				if diff := cmp.Diff(&wantRecipientUserName, recipientUserName); diff != "" {
+					t.Errorf("recipient user name mismatch (-want +got):\n%s", diff)
+				}
+				return model.SearchPage{}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/messages/search?q=invoice", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if !gotSearchMessagesCalled {
+			t.Error("search messages should have been called")
+		}
+	})
+
+	t.Run("should return 403 if sent messages of another user are requested", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/users/other-user/messages/sent", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should only get the trash of the user", func(t *testing.T) {
+		gotGetTrashCalled := false
+		service := &mock.Service{
+			GetTrashFunc: func(ctx context.Context, recipientUserName *string) ([]model.Message, error) {
+				gotGetTrashCalled = true
+				wantRecipientUserName := "user"
+				if diff := cmp.Diff(&wantRecipientUserName, recipientUserName); diff != "" {
+					t.Errorf("recipient user name mismatch (-want +got):\n%s", diff)
+				}
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/messages/trash", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if !gotGetTrashCalled {
+			t.Error("get trash should have been called")
+		}
+	})
+
+	t.Run("should return 403 if the trash of another user is requested", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/messages/trash?user_name=other-user", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should only get all messages of the user", func(t *testing.T) {
+		service := &mock.Service{
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+				wantRecipientUserName := "user"
+				if diff := cmp.Diff(&wantRecipientUserName, filter.RecipientUserName); diff != "" {
+					t.Errorf("recipient user name mismatch (-want +got):\n%s", diff)
+				}
+				return model.MessagePage{}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/messages", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should return 403 if all messages of another user are requested", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/messages?user_name=other-user", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should let admins get all messages of another user", func(t *testing.T) {
+		var gotFilter core.MessageFilter
+		service := &mock.Service{
+			AuthenticateAPIKeyFunc: func(ctx context.Context, key string) (model.User, error) {
+				return model.User{Name: "admin", Scopes: model.Scopes, Admin: true}, nil
+			},
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+				gotFilter = filter
+				return model.MessagePage{}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/messages?user_name=other-user", testServer.URL), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("X-API-Key", "msk_key")
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if gotFilter.RecipientUserName == nil || *gotFilter.RecipientUserName != "other-user" {
+			t.Errorf("got recipient user name %v, want %q", gotFilter.RecipientUserName, "other-user")
+		}
+	})
+
+	t.Run("should pass the user to the service when deleting messages", func(t *testing.T) {
+		gotDeleteMessagesCalled := false
+		service := &mock.Service{
+			DeleteMessagesFunc: func(ctx context.Context, messageIDs []string) error {
+				gotDeleteMessagesCalled = true
+				if user, _ := core.UserFromContext(ctx); user.Name != "user" {
+					t.Errorf("got user name %q, want %q", user.Name, "user")
+				}
+				return nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodDelete, fmt.Sprintf("%s/messages", testServer.URL), strings.NewReader(`{"message_ids": ["message-id"]}`))
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if !gotDeleteMessagesCalled {
+			t.Error("delete messages should have been called")
+		}
+	})
+
+	t.Run("should send messages in the name of the user", func(t *testing.T) {
+		gotSubmitMessageCalled := false
+		service := &mock.Service{
+			SubmitMessageFunc: func(ctx context.Context, submission model.MessageSubmission) (string, error) {
+				gotSubmitMessageCalled = true
+				if got, want := submission.SenderUserName, "user"; got != want {
+					t.Errorf("got sender user name %q, want %q", got, want)
+				}
+				return "message-id", nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodPost, fmt.Sprintf("%s/messages", testServer.URL), strings.NewReader(`{"recipient_user_name": "recipient", "content": "content"}`))
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if !gotSubmitMessageCalled {
+			t.Error("submit message should have been called")
+		}
+	})
+
+	t.Run("should return 403 if a message is sent in the name of another user", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodPost, fmt.Sprintf("%s/messages", testServer.URL), strings.NewReader(`{"sender_user_name": "other-user", "recipient_user_name": "recipient", "content": "content"}`))
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should return 403 if a batch contains a message in the name of another user", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodPost, fmt.Sprintf("%s/messages/batch", testServer.URL), strings.NewReader(`[
+			{"sender_user_name": "user", "recipient_user_name": "recipient", "content": "content"},
+			{"sender_user_name": "other-user", "recipient_user_name": "recipient", "content": "content"}
+		]`))
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
+
+func TestHandler_AuthenticateAPIKey(t *testing.T) {
+	t.Run("should put the user of the API key into the request context", func(t *testing.T) {
+		wantUser := model.User{Name: "user", Scopes: []string{model.ScopeMessagesRead}}
+
+		var gotUser model.User
+		service := &mock.Service{
+			AuthenticateAPIKeyFunc: func(ctx context.Context, key string) (model.User, error) {
+				if got, want := key, "msk_key"; got != want {
+					t.Errorf("got key %q, want %q", got, want)
+				}
+				return wantUser, nil
+			},
+			FetchNewMessagesFunc: func(ctx context.Context, recipientUserName string) ([]model.Message, error) {
+				gotUser, _ = core.UserFromContext(ctx)
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/users/user/messages/new", testServer.URL), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("X-API-Key", "msk_key")
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if diff := cmp.Diff(wantUser, gotUser); diff != "" {
+			t.Errorf("user mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return 401 if the API key is unknown", func(t *testing.T) {
+		service := &mock.Service{
+			AuthenticateAPIKeyFunc: func(ctx context.Context, key string) (model.User, error) {
+				return model.User{}, model.ErrNotFound
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/messages", testServer.URL), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("X-API-Key", "msk_key")
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusUnauthorized; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"invalid API key\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 500 if the API key cannot be checked", func(t *testing.T) {
+		service := &mock.Service{
+			AuthenticateAPIKeyFunc: func(ctx context.Context, key string) (model.User, error) {
+				return model.User{}, errors.New("some error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/messages", testServer.URL), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("X-API-Key", "msk_key")
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
+
+func TestHandler_RequireScope(t *testing.T) {
+	t.Run("should return 403 if the user lacks the scope of the route", func(t *testing.T) {
+		service := &mock.Service{
+			AuthenticateAPIKeyFunc: func(ctx context.Context, key string) (model.User, error) {
+				return model.User{Name: "user", Scopes: []string{model.ScopeMessagesRead}}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/messages", testServer.URL), strings.NewReader(`{"message_ids": ["message-id"]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("X-API-Key", "msk_key")
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"scope messages:delete is required\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+}
+
+func TestHandler_RequireAdmin(t *testing.T) {
+	t.Run("should return 403 if the user is not an admin", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/api-keys", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"admin role is required\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 403 if a user who is not an admin redrives dead letters", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodPost, fmt.Sprintf("%s/dead-letters/redrive", testServer.URL), strings.NewReader(`{"message_ids": ["message-id"]}`))
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should let admins through", func(t *testing.T) {
+		service := &mock.Service{
+			AuthenticateAPIKeyFunc: func(ctx context.Context, key string) (model.User, error) {
+				return model.User{Name: "admin", Admin: true}, nil
+			},
+			GetAPIKeysFunc: func(ctx context.Context) ([]model.APIKey, error) {
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api-keys", testServer.URL), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("X-API-Key", "msk_key")
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func (h *handler) cancelScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	messageID := chi.URLParam(r, "message_id")
+
+	err := h.service.CancelScheduledMessage(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, model.ErrForbidden) {
+			respondJSONStatus(w, &HTTPError{Message: "cannot cancel messages of another sender"}, http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, model.ErrNotFound) {
+			respondJSONStatus(w, &HTTPError{Message: "message not found"}, http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, model.ErrConflict) {
+			respondJSONStatus(w, &HTTPError{Message: "message already delivered"}, http.StatusConflict)
+			return
+		}
+		h.logger.Error("error cancelling scheduled message", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error cancelling scheduled message"}, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
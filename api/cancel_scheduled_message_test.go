@@ -0,0 +1,170 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+func TestHandler_CancelScheduledMessage(t *testing.T) {
+	t.Run("should forward message id to service", func(t *testing.T) {
+		wantMessageID := "message-id"
+
+		gotCancelScheduledMessageCalled := false
+		service := &mock.Service{
+			CancelScheduledMessageFunc: func(ctx context.Context, messageID string) error {
+				gotCancelScheduledMessageCalled = true
+				if got, want := messageID, wantMessageID; got != want {
+					t.Errorf("got message id %q, want %q", got, want)
+				}
+				return nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/%s/cancel", testServer.URL, wantMessageID)
+		resp, err := testServer.Client().Post(url, "application/json", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		wantCancelScheduledMessageCalled := true
+		if got, want := gotCancelScheduledMessageCalled, wantCancelScheduledMessageCalled; got != want {
+			t.Errorf("got cancel scheduled message called %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should return 404 if message is not found", func(t *testing.T) {
+		service := &mock.Service{
+			CancelScheduledMessageFunc: func(ctx context.Context, messageID string) error {
+				return model.ErrNotFound
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/message-id/cancel", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"message not found\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 409 if message is already delivered", func(t *testing.T) {
+		service := &mock.Service{
+			CancelScheduledMessageFunc: func(ctx context.Context, messageID string) error {
+				return model.ErrConflict
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/message-id/cancel", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusConflict; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"message already delivered\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 403 if message was sent by another user", func(t *testing.T) {
+		service := &mock.Service{
+			CancelScheduledMessageFunc: func(ctx context.Context, messageID string) error {
+				return model.ErrForbidden
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/message-id/cancel", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"cannot cancel messages of another sender\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns an error", func(t *testing.T) {
+		service := &mock.Service{
+			CancelScheduledMessageFunc: func(ctx context.Context, messageID string) error {
+				return errors.New("some error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/message-id/cancel", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"error cancelling scheduled message\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+}
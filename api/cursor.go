@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+)
+
+// pageCursor is the representation of a model.PageCursor that is encoded into
+// the opaque cursors handed out to clients.
+type pageCursor struct {
+	SentAt time.Time `json:"s"`
+	ID     string    `json:"i"`
+	Before bool      `json:"b,omitempty"`
+}
+
+func encodeCursor(cursor *model.PageCursor) string {
+	if cursor == nil {
+		return ""
+	}
+
+	data, _ := json.Marshal(pageCursor(*cursor))
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (*model.PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode base64: %w", err)
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+
+	if cursor.ID == "" {
+		return nil, fmt.Errorf("cursor without message ID")
+	}
+
+	modelCursor := model.PageCursor(cursor)
+	return &modelCursor, nil
+}
+
+// searchCursor is encoded into the opaque cursors of search results, which are
+// paged by offset.
+type searchCursor struct {
+	Offset int `json:"o"`
+}
+
+func encodeSearchCursor(offset *int) string {
+	if offset == nil {
+		return ""
+	}
+
+	data, _ := json.Marshal(searchCursor{Offset: *offset})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(raw string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return 0, fmt.Errorf("decode base64: %w", err)
+	}
+
+	var cursor searchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return 0, fmt.Errorf("decode json: %w", err)
+	}
+
+	if cursor.Offset < 0 {
+		return 0, fmt.Errorf("negative offset")
+	}
+
+	return cursor.Offset, nil
+}
@@ -10,9 +10,28 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	deleteModeStrict     = "strict"
+	deleteModeBestEffort = "best_effort"
+)
+
+type missingMessagesHTTPError struct {
+	HTTPError
+	MissingMessageIDs []string `json:"missing"`
+}
+
 func (h *handler) deleteMessages(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = deleteModeStrict
+	}
+	if mode != deleteModeStrict && mode != deleteModeBestEffort {
+		respondJSONStatus(w, &HTTPError{Message: fmt.Sprintf("mode must be %s or %s", deleteModeStrict, deleteModeBestEffort)}, http.StatusBadRequest)
+		return
+	}
+
 	reqBody := struct {
 		MessageIDs []string `json:"message_ids"`
 	}{}
@@ -27,8 +46,25 @@ func (h *handler) deleteMessages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if mode == deleteModeBestEffort {
+		h.deleteExistingMessages(w, r, reqBody.MessageIDs)
+		return
+	}
+
 	err := h.service.DeleteMessages(ctx, reqBody.MessageIDs)
 	if err != nil {
+		if errors.Is(err, model.ErrForbidden) {
+			respondJSONStatus(w, &HTTPError{Message: "cannot delete messages of another user"}, http.StatusForbidden)
+			return
+		}
+		var missingErr *model.MissingMessagesError
+		if errors.As(err, &missingErr) {
+			respondJSONStatus(w, &missingMessagesHTTPError{
+				HTTPError:         HTTPError{Message: "message not found"},
+				MissingMessageIDs: missingErr.MessageIDs,
+			}, http.StatusNotFound)
+			return
+		}
 		if errors.Is(err, model.ErrNotFound) {
 			respondJSONStatus(w, &HTTPError{Message: "message not found"}, http.StatusNotFound)
 			return
@@ -40,3 +76,25 @@ func (h *handler) deleteMessages(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// deleteExistingMessages deletes the messages that exist and tells which were
+// missing instead of failing the whole request.
+func (h *handler) deleteExistingMessages(w http.ResponseWriter, r *http.Request, messageIDs []string) {
+	result, err := h.service.DeleteExistingMessages(r.Context(), messageIDs)
+	if err != nil {
+		if errors.Is(err, model.ErrForbidden) {
+			respondJSONStatus(w, &HTTPError{Message: "cannot delete messages of another user"}, http.StatusForbidden)
+			return
+		}
+		h.logger.Error("error deleting messages", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error deleting messages"}, http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if len(result.Missing) > 0 {
+		status = http.StatusMultiStatus
+	}
+
+	respondJSONStatus(w, &result, status)
+}
@@ -149,6 +149,40 @@ func TestHandler_DeleteMessages(t *testing.T) {
 		}
 	})
 
+	t.Run("should return 403 if messages belong to another user", func(t *testing.T) {
+		service := &mock.Service{
+			DeleteMessagesFunc: func(ctx context.Context, messageIDs []string) error {
+				return model.ErrForbidden
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages", testServer.URL)
+		req, err := http.NewRequest(http.MethodDelete, url, strings.NewReader(`{"message_ids": ["message-id-1"]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"cannot delete messages of another user\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
 	t.Run("should return 500 if service returns an error", func(t *testing.T) {
 		service := &mock.Service{
 			DeleteMessagesFunc: func(ctx context.Context, messageIDs []string) error {
@@ -182,4 +216,167 @@ func TestHandler_DeleteMessages(t *testing.T) {
 			t.Errorf("got response body %q, want %q", got, want)
 		}
 	})
+	t.Run("should return 404 listing missing message IDs", func(t *testing.T) {
+		service := &mock.Service{
+			DeleteMessagesFunc: func(ctx context.Context, messageIDs []string) error {
+				return fmt.Errorf("delete messages: %w", &model.MissingMessagesError{MessageIDs: []string{"message-id-2"}})
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages?mode=strict", testServer.URL)
+		req, err := http.NewRequest(http.MethodDelete, url, strings.NewReader(`{"message_ids": ["message-id-1", "message-id-2"]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"message not found\",\"missing\":[\"message-id-2\"]}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 400 if mode is invalid", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages?mode=lenient", testServer.URL)
+		req, err := http.NewRequest(http.MethodDelete, url, strings.NewReader(`{"message_ids": ["message-id-1"]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"mode must be strict or best_effort\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+}
+
+func TestHandler_DeleteMessages_BestEffort(t *testing.T) {
+	t.Run("should return 207 listing deleted and missing message IDs", func(t *testing.T) {
+		wantMessageIDs := []string{"message-id-1", "message-id-2"}
+
+		service := &mock.Service{
+			DeleteExistingMessagesFunc: func(ctx context.Context, messageIDs []string) (model.DeleteResult, error) {
+				if got, want := messageIDs, wantMessageIDs; !reflect.DeepEqual(got, want) {
+					t.Errorf("got message ids %v, want %v", got, want)
+				}
+				return model.DeleteResult{Deleted: []string{"message-id-1"}, Missing: []string{"message-id-2"}}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages?mode=best_effort", testServer.URL)
+		reqBody := fmt.Sprintf(`{"message_ids": ["%s", "%s"]}`, wantMessageIDs[0], wantMessageIDs[1])
+		req, err := http.NewRequest(http.MethodDelete, url, strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusMultiStatus; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"deleted\":[\"message-id-1\"],\"missing\":[\"message-id-2\"]}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 200 if all messages are deleted", func(t *testing.T) {
+		service := &mock.Service{
+			DeleteExistingMessagesFunc: func(ctx context.Context, messageIDs []string) (model.DeleteResult, error) {
+				return model.DeleteResult{Deleted: []string{"message-id-1"}, Missing: []string{}}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages?mode=best_effort", testServer.URL)
+		req, err := http.NewRequest(http.MethodDelete, url, strings.NewReader(`{"message_ids": ["message-id-1"]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"deleted\":[\"message-id-1\"],\"missing\":[]}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns an error", func(t *testing.T) {
+		service := &mock.Service{
+			DeleteExistingMessagesFunc: func(ctx context.Context, messageIDs []string) (model.DeleteResult, error) {
+				return model.DeleteResult{}, errors.New("some error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages?mode=best_effort", testServer.URL)
+		req, err := http.NewRequest(http.MethodDelete, url, strings.NewReader(`{"message_ids": ["message-id-1"]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
 }
@@ -2,28 +2,49 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
 	"github.com/RichterMaximilian/osttra-coding-assignment/model"
 	"go.uber.org/zap"
 )
 
+// maxLimit is the largest number of messages a client may request per page.
+const maxLimit = 1000
+
+type messagePageResponse struct {
+	Messages   []model.Message `json:"messages"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	PrevCursor string          `json:"prev_cursor,omitempty"`
+}
+
 func (h *handler) getAllMessages(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	startCursorRaw := r.URL.Query().Get("start_cursor")
-	var startCursor *string
-	if startCursorRaw != "" {
-		startCursor = &startCursorRaw
+	filter, errMessage := parseMessageFilter(r)
+	if errMessage != "" {
+		respondJSONStatus(w, &HTTPError{Message: errMessage}, http.StatusBadRequest)
+		return
+	}
+
+	if userName := core.RestrictedUserName(ctx); userName != nil {
+		if filter.RecipientUserName != nil && *filter.RecipientUserName != *userName {
+			respondJSONStatus(w, &HTTPError{Message: "cannot get messages of another user"}, http.StatusForbidden)
+			return
+		}
+		filter.RecipientUserName = userName
 	}
 
-	endCursorRaw := r.URL.Query().Get("end_cursor")
-	var endCursor *string
-	if endCursorRaw != "" {
-		endCursor = &endCursorRaw
+	page, errMessage := parsePageRequest(r)
+	if errMessage != "" {
+		respondJSONStatus(w, &HTTPError{Message: errMessage}, http.StatusBadRequest)
+		return
 	}
 
-	messages, err := h.service.GetAllMessages(ctx, startCursor, endCursor)
+	messagePage, err := h.service.GetAllMessages(ctx, filter, page)
 	if err != nil {
 		if errors.Is(err, model.ErrNotFound) {
 			respondJSONStatus(w, &HTTPError{Message: "message not found"}, http.StatusNotFound)
@@ -34,9 +55,108 @@ func (h *handler) getAllMessages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	respondJSONStatus(w, newMessagePageResponse(messagePage), http.StatusOK)
+}
+
+// parseMessageFilter reads the query parameters filtering the messages. It
+// returns a message for the client if they are invalid.
+func parseMessageFilter(r *http.Request) (core.MessageFilter, string) {
+	query := r.URL.Query()
+	var filter core.MessageFilter
+
+	filter.StartCursor = optionalQueryParam(query.Get("start_cursor"))
+	filter.EndCursor = optionalQueryParam(query.Get("end_cursor"))
+	filter.RecipientUserName = optionalQueryParam(query.Get("user_name"))
+	filter.ContentContains = optionalQueryParam(query.Get("content"))
+
+	if fetchedRaw := query.Get("fetched"); fetchedRaw != "" {
+		fetched, err := strconv.ParseBool(fetchedRaw)
+		if err != nil {
+			return filter, "fetched must be true or false"
+		}
+		filter.Fetched = &fetched
+	}
+
+	var errMessage string
+	if filter.SentAfter, errMessage = parseTimeQueryParam(r, "sent_after"); errMessage != "" {
+		return filter, errMessage
+	}
+
+	if filter.SentBefore, errMessage = parseTimeQueryParam(r, "sent_before"); errMessage != "" {
+		return filter, errMessage
+	}
+
+	return filter, ""
+}
+
+func parseTimeQueryParam(r *http.Request, name string) (*time.Time, string) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, ""
+	}
+
+	value, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return nil, fmt.Sprintf("%s must be an RFC 3339 timestamp", name)
+	}
+
+	return &value, ""
+}
+
+func optionalQueryParam(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
+
+// parsePageRequest reads the limit and cursor query parameters. It returns a
+// message for the client if they are invalid.
+func parsePageRequest(r *http.Request) (model.PageRequest, string) {
+	var page model.PageRequest
+
+	var errMessage string
+	if page.Limit, errMessage = parseLimit(r); errMessage != "" {
+		return page, errMessage
+	}
+
+	if cursorRaw := r.URL.Query().Get("cursor"); cursorRaw != "" {
+		cursor, err := decodeCursor(cursorRaw)
+		if err != nil {
+			return page, "cursor is invalid"
+		}
+		page.Cursor = cursor
+	}
+
+	return page, ""
+}
+
+// parseLimit reads the limit query parameter, which is zero if it is not
+// given. It returns a message for the client if it is invalid.
+func parseLimit(r *http.Request) (int, string) {
+	limitRaw := r.URL.Query().Get("limit")
+	if limitRaw == "" {
+		return 0, ""
+	}
+
+	limit, err := strconv.Atoi(limitRaw)
+	if err != nil || limit < 1 || limit > maxLimit {
+		return 0, fmt.Sprintf("limit must be between 1 and %d", maxLimit)
+	}
+
+	return limit, ""
+}
+
+func newMessagePageResponse(page model.MessagePage) *messagePageResponse {
+	messages := page.Messages
 	if messages == nil {
 		messages = []model.Message{}
 	}
 
-	respondJSONStatus(w, &messages, http.StatusOK)
+	return &messagePageResponse{
+		Messages:   messages,
+		NextCursor: encodeCursor(page.NextCursor),
+		PrevCursor: encodeCursor(page.PrevCursor),
+	}
 }
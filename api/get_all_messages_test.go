@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
 	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
 	"github.com/RichterMaximilian/osttra-coding-assignment/model"
 	"github.com/google/go-cmp/cmp"
@@ -25,16 +26,16 @@ func TestHandler_GetAllMessages(t *testing.T) {
 
 		gotGetAllMessagesCalled := false
 		service := &mock.Service{
-			GetAllMessagesFunc: func(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error) {
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
 				gotGetAllMessagesCalled = true
-				if diff := cmp.Diff(&wantStartCursor, startCursor); diff != "" {
+				if diff := cmp.Diff(&wantStartCursor, filter.StartCursor); diff != "" {
 					t.Errorf("start cursor mismatch (-want +got):\n%s", diff)
 				}
 
-				if diff := cmp.Diff(&wantEndCursor, endCursor); diff != "" {
+				if diff := cmp.Diff(&wantEndCursor, filter.EndCursor); diff != "" {
 					t.Errorf("end cursor mismatch (-want +got):\n%s", diff)
 				}
-				return nil, nil
+				return model.MessagePage{}, nil
 			},
 		}
 
@@ -56,6 +57,78 @@ func TestHandler_GetAllMessages(t *testing.T) {
 		}
 	})
 
+	t.Run("should forward filters to service", func(t *testing.T) {
+		recipientUserName, content, fetched := "recipient", "hello world", false
+		sentAfter := time.Date(2023, 4, 13, 19, 0, 0, 0, time.UTC)
+		sentBefore := time.Date(2023, 4, 14, 19, 0, 0, 0, time.UTC)
+		wantFilter := core.MessageFilter{
+			RecipientUserName: &recipientUserName,
+			Fetched:           &fetched,
+			SentAfter:         &sentAfter,
+			SentBefore:        &sentBefore,
+			ContentContains:   &content,
+		}
+
+		gotGetAllMessagesCalled := false
+		service := &mock.Service{
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+				gotGetAllMessagesCalled = true
+				if diff := cmp.Diff(wantFilter, filter); diff != "" {
+					t.Errorf("filter mismatch (-want +got):\n%s", diff)
+				}
+				return model.MessagePage{}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages?user_name=recipient&fetched=false&sent_after=2023-04-13T19:00:00Z&sent_before=2023-04-14T19:00:00Z&content=hello+world", testServer.URL)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		wantGetAllMessagesCalled := true
+		if got, want := gotGetAllMessagesCalled, wantGetAllMessagesCalled; got != want {
+			t.Errorf("got GetAllMessages called %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should return 400 if filter is invalid", func(t *testing.T) {
+		for query, wantMessage := range map[string]string{
+			"fetched=maybe":       "fetched must be true or false",
+			"sent_after=today":    "sent_after must be an RFC 3339 timestamp",
+			"sent_before=2023-04": "sent_before must be an RFC 3339 timestamp",
+		} {
+			service := &mock.Service{}
+
+			testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+			resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages?%s", testServer.URL, query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+				t.Errorf("got HTTP status %d for %q, want %d", got, query, want)
+			}
+
+			respBody, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			wantRespBody := fmt.Sprintf("{\"message\":%q}\n", wantMessage)
+			if got, want := string(respBody), wantRespBody; got != want {
+				t.Errorf("got response body %q, want %q", got, want)
+			}
+		}
+	})
+
 	t.Run("should return messages from service", func(t *testing.T) {
 		wantMessages := []model.Message{
 			{
@@ -73,8 +146,8 @@ func TestHandler_GetAllMessages(t *testing.T) {
 		}
 
 		service := &mock.Service{
-			GetAllMessagesFunc: func(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error) {
-				return wantMessages, nil
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+				return model.MessagePage{Messages: wantMessages}, nil
 			},
 		}
 
@@ -90,10 +163,13 @@ func TestHandler_GetAllMessages(t *testing.T) {
 			t.Errorf("got HTTP status %d, want %d", got, want)
 		}
 
-		var gotMessages []model.Message
-		if err := json.NewDecoder(resp.Body).Decode(&gotMessages); err != nil {
+		var gotPage struct {
+			Messages []model.Message `json:"messages"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&gotPage); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
+		gotMessages := gotPage.Messages
 
 		if diff := cmp.Diff(gotMessages, wantMessages); diff != "" {
 			t.Errorf("got messages %v, want %v, diff %s", gotMessages, wantMessages, diff)
@@ -102,8 +178,8 @@ func TestHandler_GetAllMessages(t *testing.T) {
 
 	t.Run("should return empty array if no messages are found", func(t *testing.T) {
 		service := &mock.Service{
-			GetAllMessagesFunc: func(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error) {
-				return nil, nil
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+				return model.MessagePage{}, nil
 			},
 		}
 
@@ -124,7 +200,108 @@ func TestHandler_GetAllMessages(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		wantRespBody := "[]\n"
+		wantRespBody := "{\"messages\":[]}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return cursors that are accepted for the adjacent pages", func(t *testing.T) {
+		sentAt := time.Date(2023, 4, 13, 19, 43, 23, 123456000, time.UTC)
+		wantNextCursor := &model.PageCursor{SentAt: sentAt, ID: "id-2"}
+		wantPrevCursor := &model.PageCursor{SentAt: sentAt, ID: "id-1", Before: true}
+
+		var gotPages []model.PageRequest
+		service := &mock.Service{
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+				gotPages = append(gotPages, page)
+				return model.MessagePage{NextCursor: wantNextCursor, PrevCursor: wantPrevCursor}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages?limit=2", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var gotPage struct {
+			NextCursor string `json:"next_cursor"`
+			PrevCursor string `json:"prev_cursor"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&gotPage); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, cursor := range []string{gotPage.NextCursor, gotPage.PrevCursor} {
+			resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages?limit=2&cursor=%s", testServer.URL, cursor))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got, want := resp.StatusCode, http.StatusOK; got != want {
+				t.Errorf("got HTTP status %d, want %d", got, want)
+			}
+		}
+
+		wantPages := []model.PageRequest{
+			{Limit: 2},
+			{Limit: 2, Cursor: wantNextCursor},
+			{Limit: 2, Cursor: wantPrevCursor},
+		}
+		if diff := cmp.Diff(wantPages, gotPages); diff != "" {
+			t.Errorf("pages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return 400 if limit is invalid", func(t *testing.T) {
+		for _, limit := range []string{"many", "0", "1001"} {
+			service := &mock.Service{}
+
+			testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+			resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages?limit=%s", testServer.URL, limit))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+				t.Errorf("got HTTP status %d for limit %q, want %d", got, limit, want)
+			}
+
+			respBody, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			wantRespBody := "{\"message\":\"limit must be between 1 and 1000\"}\n"
+			if got, want := string(respBody), wantRespBody; got != want {
+				t.Errorf("got response body %q, want %q", got, want)
+			}
+		}
+	})
+
+	t.Run("should return 400 if cursor is invalid", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages?cursor=invalid", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"cursor is invalid\"}\n"
 		if got, want := string(respBody), wantRespBody; got != want {
 			t.Errorf("got response body %q, want %q", got, want)
 		}
@@ -132,8 +309,8 @@ func TestHandler_GetAllMessages(t *testing.T) {
 
 	t.Run("should return 404 if no messages are found", func(t *testing.T) {
 		service := &mock.Service{
-			GetAllMessagesFunc: func(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error) {
-				return nil, model.ErrNotFound
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+				return model.MessagePage{}, model.ErrNotFound
 			},
 		}
 
@@ -162,8 +339,8 @@ func TestHandler_GetAllMessages(t *testing.T) {
 
 	t.Run("should return 500 if service returns error", func(t *testing.T) {
 		service := &mock.Service{
-			GetAllMessagesFunc: func(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error) {
-				return nil, errors.New("some error")
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+				return model.MessagePage{}, errors.New("some error")
 			},
 		}
 
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+func (h *handler) getAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	apiKeys, err := h.service.GetAPIKeys(ctx)
+	if err != nil {
+		h.logger.Error("error getting api keys", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error getting API keys"}, http.StatusInternalServerError)
+		return
+	}
+
+	if apiKeys == nil {
+		apiKeys = []model.APIKey{}
+	}
+
+	respondJSONStatus(w, &apiKeys, http.StatusOK)
+}
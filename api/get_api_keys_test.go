@@ -0,0 +1,101 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestHandler_GetAPIKeys(t *testing.T) {
+	t.Run("should return API keys from service", func(t *testing.T) {
+		wantAPIKeys := []model.APIKey{
+			{
+				ID:        "api-key-id",
+				UserName:  "user",
+				Scopes:    []string{model.ScopeMessagesRead},
+				CreatedAt: time.Now(),
+			},
+		}
+
+		service := &mock.Service{
+			GetAPIKeysFunc: func(ctx context.Context) ([]model.APIKey, error) {
+				return wantAPIKeys, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/api-keys", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		var gotAPIKeys []model.APIKey
+		if err := json.NewDecoder(resp.Body).Decode(&gotAPIKeys); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantAPIKeys, gotAPIKeys); diff != "" {
+			t.Errorf("api keys mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return empty array if there are no API keys", func(t *testing.T) {
+		service := &mock.Service{
+			GetAPIKeysFunc: func(ctx context.Context) ([]model.APIKey, error) {
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/api-keys", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "[]\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns error", func(t *testing.T) {
+		service := &mock.Service{
+			GetAPIKeysFunc: func(ctx context.Context) ([]model.APIKey, error) {
+				return nil, fmt.Errorf("service error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/api-keys", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
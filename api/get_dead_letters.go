@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+func (h *handler) getDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	recipientUserNameRaw := r.URL.Query().Get("user_name")
+	var recipientUserName *string
+	if recipientUserNameRaw != "" {
+		recipientUserName = &recipientUserNameRaw
+	}
+
+	deadLetters, err := h.service.GetDeadLetters(ctx, recipientUserName)
+	if err != nil {
+		h.logger.Error("error fetching dead letters", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error fetching dead letters"}, http.StatusInternalServerError)
+		return
+	}
+
+	if deadLetters == nil {
+		deadLetters = []model.DeadLetter{}
+	}
+
+	respondJSONStatus(w, &deadLetters, http.StatusOK)
+}
@@ -0,0 +1,154 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestHandler_GetDeadLetters(t *testing.T) {
+	t.Run("should forward user name to service", func(t *testing.T) {
+		wantRecipientUserName := "recipient"
+
+		gotGetDeadLettersCalled := false
+		service := &mock.Service{
+			GetDeadLettersFunc: func(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+				gotGetDeadLettersCalled = true
+				if diff := cmp.Diff(&wantRecipientUserName, recipientUserName); diff != "" {
+					t.Errorf("recipient user name mismatch (-want +got):\n%s", diff)
+				}
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/dead-letters?user_name=%s", testServer.URL, wantRecipientUserName)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		wantGetDeadLettersCalled := true
+		if got, want := gotGetDeadLettersCalled, wantGetDeadLettersCalled; got != want {
+			t.Errorf("got GetDeadLetters called %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should return dead letters from service", func(t *testing.T) {
+		wantDeadLetters := []model.DeadLetter{
+			{
+				Message: model.Message{
+					ID:                "id-1",
+					RecipientUserName: "recipient-1",
+					Content:           "content-1",
+					SentAt:            time.Now(),
+					ReceiveCount:      5,
+				},
+				DeadLetteredAt: time.Now(),
+			},
+		}
+
+		service := &mock.Service{
+			GetDeadLettersFunc: func(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+				return wantDeadLetters, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/dead-letters", testServer.URL)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		var gotDeadLetters []model.DeadLetter
+		if err := json.NewDecoder(resp.Body).Decode(&gotDeadLetters); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(gotDeadLetters, wantDeadLetters); diff != "" {
+			t.Errorf("dead letters mismatch (-got +want): %s", diff)
+		}
+	})
+
+	t.Run("should return empty array if no dead letters are available", func(t *testing.T) {
+		service := &mock.Service{
+			GetDeadLettersFunc: func(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/dead-letters", testServer.URL)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "[]\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns error", func(t *testing.T) {
+		service := &mock.Service{
+			GetDeadLettersFunc: func(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+				return nil, fmt.Errorf("service error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/dead-letters", testServer.URL)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"error fetching dead letters\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func (h *handler) getMessage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	messageID := chi.URLParam(r, "message_id")
+
+	message, err := h.service.GetMessage(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			respondJSONStatus(w, &HTTPError{Message: "message not found"}, http.StatusNotFound)
+			return
+		}
+		h.logger.Error("error fetching message", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error fetching message"}, http.StatusInternalServerError)
+		return
+	}
+
+	// Messages of other users are reported as missing, so that their IDs do
+	// not reveal anything.
+	if userName := core.RestrictedUserName(ctx); userName != nil && *userName != message.RecipientUserName && *userName != message.SenderUserName {
+		respondJSONStatus(w, &HTTPError{Message: "message not found"}, http.StatusNotFound)
+		return
+	}
+
+	body, err := json.Marshal(&message)
+	if err != nil {
+		h.logger.Error("error encoding message", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error fetching message"}, http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+
+	// The ETag is derived from the whole representation, so it changes as soon
+	// as anything about the message changes, e.g. when it is fetched.
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:16]))
+	w.Header().Set("ETag", etag)
+
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// matchesETag reports whether the value of an If-None-Match header contains
+// the ETag. Weak validators match as well, as is required for GET requests.
+func matchesETag(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
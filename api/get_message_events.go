@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+// messageEventsPageSize is the number of messages read at once, so that clients
+// resuming after a long time do not load all their messages into memory.
+const messageEventsPageSize = 100
+
+func (h *handler) getMessageEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	recipientUserName := r.URL.Query().Get("user_name")
+	if recipientUserName == "" {
+		respondJSONStatus(w, &HTTPError{Message: "user_name is required"}, http.StatusBadRequest)
+		return
+	}
+
+	if user, ok := core.UserFromContext(ctx); ok && user.Name != recipientUserName {
+		respondJSONStatus(w, &HTTPError{Message: "cannot get message events of another user"}, http.StatusForbidden)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.logger.Error("response writer does not support flushing")
+		respondJSONStatus(w, &HTTPError{Message: "streaming not supported"}, http.StatusInternalServerError)
+		return
+	}
+
+	// Clients reconnecting after an interruption send the ID of the last
+	// message they received, which is used as the cursor to resume from.
+	var cursor *string
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		cursor = &lastEventID
+	}
+
+	ctx, cancel := h.streamContext(ctx)
+	defer cancel()
+
+	events := h.service.SubscribeNewMessages(ctx, recipientUserName)
+
+	messages, err := h.service.GetReceivedMessages(ctx, recipientUserName, cursor, messageEventsPageSize)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			respondJSONStatus(w, &HTTPError{Message: "message not found"}, http.StatusNotFound)
+			return
+		}
+		h.logger.Error("error fetching received messages", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error fetching received messages"}, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	heartbeat := time.NewTicker(h.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	poll := time.NewTicker(streamPollInterval)
+	defer poll.Stop()
+
+	for {
+		for _, message := range messages {
+			if err := writeMessageEvent(w, message); err != nil {
+				// The client has gone away.
+				return
+			}
+			messageID := message.ID
+			cursor = &messageID
+		}
+		flusher.Flush()
+
+		// A full page means that more messages may be waiting already.
+		if len(messages) < messageEventsPageSize && !waitForNewMessages(ctx, w, flusher, events, heartbeat.C, poll.C) {
+			return
+		}
+
+		messages, err = h.service.GetReceivedMessages(ctx, recipientUserName, cursor, messageEventsPageSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				h.logger.Error("error fetching received messages", zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+// waitForNewMessages blocks until new messages may be available and sends
+// heartbeats in the meantime. It returns false once the stream has ended.
+func waitForNewMessages(ctx context.Context, w io.Writer, flusher http.Flusher, events <-chan model.MessageEvent, heartbeat, poll <-chan time.Time) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-heartbeat:
+			// Comments are ignored by clients but keep the connection busy.
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return false
+			}
+			flusher.Flush()
+		case <-events:
+			return true
+		case <-poll:
+			return true
+		}
+	}
+}
+
+func writeMessageEvent(w io.Writer, message model.Message) error {
+	data, err := json.Marshal(&message)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, "id: %s\nevent: message\ndata: %s\n\n", message.ID, data); err != nil {
+		return fmt.Errorf("write event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+func TestHandler_GetMessageEvents(t *testing.T) {
+	t.Run("should stream messages as events", func(t *testing.T) {
+		now := time.Date(2023, 4, 13, 19, 43, 23, 0, time.UTC)
+
+		events := make(chan model.MessageEvent)
+		var gotCursors []*string
+		service := &mock.Service{
+			SubscribeNewMessagesFunc: func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+				return events
+			},
+			GetReceivedMessagesFunc: func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+				if got, want := recipientUserName, "recipient"; got != want {
+					t.Errorf("got recipient user name %q, want %q", got, want)
+				}
+				gotCursors = append(gotCursors, afterCursor)
+				messageID := fmt.Sprintf("id%d", len(gotCursors))
+				return []model.Message{{ID: messageID, RecipientUserName: "recipient", Content: "content", SentAt: now}}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+		defer testServer.Close()
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/events?user_name=recipient", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if got, want := resp.Header.Get("Content-Type"), "text/event-stream"; got != want {
+			t.Errorf("got content type %q, want %q", got, want)
+		}
+
+		reader := bufio.NewReader(resp.Body)
+
+		wantEvent := "id: id1\nevent: message\ndata: {\"id\":\"id1\",\"sender_user_name\":\"\",\"recipient_user_name\":\"recipient\",\"content\":\"content\",\"sent_at\":\"2023-04-13T19:43:23Z\",\"receive_count\":0}\n\n"
+		if got, want := readEvent(t, reader), wantEvent; got != want {
+			t.Errorf("got event %q, want %q", got, want)
+		}
+
+		events <- model.MessageEvent{MessageID: "id2", RecipientUserName: "recipient"}
+
+		if got, want := readEvent(t, reader), strings.ReplaceAll(wantEvent, "id1", "id2"); got != want {
+			t.Errorf("got event %q, want %q", got, want)
+		}
+
+		if got := gotCursors[0]; got != nil {
+			t.Errorf("got first cursor %q, want nil", *got)
+		}
+		if got, want := *gotCursors[1], "id1"; got != want {
+			t.Errorf("got second cursor %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should read the next page right away if a page is full", func(t *testing.T) {
+		service := &mock.Service{
+			SubscribeNewMessagesFunc: func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+				return nil
+			},
+			GetReceivedMessagesFunc: func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+				if afterCursor != nil {
+					return []model.Message{{ID: "next", RecipientUserName: "recipient"}}, nil
+				}
+				messages := make([]model.Message, limit)
+				for i := range messages {
+					messages[i] = model.Message{ID: fmt.Sprintf("id%d", i), RecipientUserName: "recipient"}
+				}
+				return messages, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+		defer testServer.Close()
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/events?user_name=recipient", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		reader := bufio.NewReader(resp.Body)
+
+		var event string
+		for !strings.HasPrefix(event, "id: next\n") {
+			event = readEvent(t, reader)
+		}
+	})
+
+	t.Run("should resume after last event ID", func(t *testing.T) {
+		wantCursor := "id"
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		gotGetReceivedMessagesCalled := false
+		service := &mock.Service{
+			SubscribeNewMessagesFunc: func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+				return nil
+			},
+			GetReceivedMessagesFunc: func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+				gotGetReceivedMessagesCalled = true
+				if afterCursor == nil || *afterCursor != wantCursor {
+					t.Errorf("got cursor %v, want %q", afterCursor, wantCursor)
+				}
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+		defer testServer.Close()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/messages/events?user_name=recipient", testServer.URL), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("Last-Event-ID", wantCursor)
+
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		wantGetReceivedMessagesCalled := true
+		if got, want := gotGetReceivedMessagesCalled, wantGetReceivedMessagesCalled; got != want {
+			t.Errorf("got get received messages called %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should send heartbeats", func(t *testing.T) {
+		service := &mock.Service{
+			SubscribeNewMessagesFunc: func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+				return nil
+			},
+			GetReceivedMessagesFunc: func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterHeartbeatInterval(time.Millisecond)))
+		defer testServer.Close()
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/events?user_name=recipient", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if got, want := readEvent(t, bufio.NewReader(resp.Body)), ": heartbeat\n\n"; got != want {
+			t.Errorf("got event %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should end the stream when the server shuts down", func(t *testing.T) {
+		service := &mock.Service{
+			SubscribeNewMessagesFunc: func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+				return nil
+			},
+			GetReceivedMessagesFunc: func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+				return nil, nil
+			},
+		}
+
+		shutdown := make(chan struct{})
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterShutdown(shutdown)))
+		defer testServer.Close()
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/events?user_name=recipient", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		close(shutdown)
+
+		if _, err := io.ReadAll(resp.Body); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should return 404 if last event ID is not found", func(t *testing.T) {
+		service := &mock.Service{
+			SubscribeNewMessagesFunc: func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+				return nil
+			},
+			GetReceivedMessagesFunc: func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+				return nil, model.ErrNotFound
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+		defer testServer.Close()
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/messages/events?user_name=recipient", testServer.URL), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("Last-Event-ID", "id")
+
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should return 400 if user name is missing", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+		defer testServer.Close()
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/events", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns error", func(t *testing.T) {
+		service := &mock.Service{
+			SubscribeNewMessagesFunc: func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+				return nil
+			},
+			GetReceivedMessagesFunc: func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+				return nil, errors.New("error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+		defer testServer.Close()
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/events?user_name=recipient", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
+
+// readEvent reads lines up to and including the empty line ending an event.
+func readEvent(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+
+	var event strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		event.WriteString(line)
+		if line == "\n" {
+			return event.String()
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestHandler_GetMessage(t *testing.T) {
+	wantMessage := model.Message{
+		ID:                "message-id",
+		SenderUserName:    "sender",
+		RecipientUserName: "recipient",
+		Content:           "content",
+		SentAt:            time.Date(2023, 4, 13, 19, 43, 23, 0, time.UTC),
+	}
+
+	t.Run("should return message from service with ETag", func(t *testing.T) {
+		service := &mock.Service{
+			GetMessageFunc: func(ctx context.Context, messageID string) (model.Message, error) {
+				if got, want := messageID, wantMessage.ID; got != want {
+					t.Errorf("got message ID %q, want %q", got, want)
+				}
+				return wantMessage, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/%s", testServer.URL, wantMessage.ID))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if resp.Header.Get("ETag") == "" {
+			t.Error("got no ETag")
+		}
+
+		var gotMessage model.Message
+		if err := json.NewDecoder(resp.Body).Decode(&gotMessage); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantMessage, gotMessage); diff != "" {
+			t.Errorf("message mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return 304 if ETag matches", func(t *testing.T) {
+		message := wantMessage
+		service := &mock.Service{
+			GetMessageFunc: func(ctx context.Context, messageID string) (model.Message, error) {
+				return message, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+		url := fmt.Sprintf("%s/messages/%s", testServer.URL, wantMessage.ID)
+
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		etag := resp.Header.Get("ETag")
+
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("If-None-Match", fmt.Sprintf(`"other", W/%s`, etag))
+
+		resp, err = testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNotModified; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if got, want := resp.Header.Get("ETag"), etag; got != want {
+			t.Errorf("got ETag %q, want %q", got, want)
+		}
+
+		fetchedAt := time.Now()
+		message.FetchedAt = &fetchedAt
+
+		resp, err = testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d after message changed, want %d", got, want)
+		}
+	})
+
+	t.Run("should return 404 if message is not found", func(t *testing.T) {
+		service := &mock.Service{
+			GetMessageFunc: func(ctx context.Context, messageID string) (model.Message, error) {
+				return model.Message{}, model.ErrNotFound
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/message-id", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"message not found\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns error", func(t *testing.T) {
+		service := &mock.Service{
+			GetMessageFunc: func(ctx context.Context, messageID string) (model.Message, error) {
+				return model.Message{}, errors.New("error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/message-id", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
@@ -1,17 +1,56 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
 	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
+// maxWait is the longest duration a client may wait for new messages, which
+// keeps long polls below common proxy timeouts.
+const maxWait = time.Minute
+
 func (h *handler) getNewMessages(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	messages, err := h.service.FetchNewMessages(ctx)
+	recipientUserName := chi.URLParam(r, "user_name")
+	if recipientUserName == "" {
+		respondJSONStatus(w, &HTTPError{Message: "user_name is required"}, http.StatusBadRequest)
+		return
+	}
+
+	if user, ok := core.UserFromContext(ctx); ok && user.Name != recipientUserName {
+		respondJSONStatus(w, &HTTPError{Message: "cannot fetch messages of another user"}, http.StatusForbidden)
+		return
+	}
+
+	var wait time.Duration
+	if waitRaw := r.URL.Query().Get("wait"); waitRaw != "" {
+		var err error
+		wait, err = time.ParseDuration(waitRaw)
+		if err != nil || wait <= 0 || wait > maxWait {
+			respondJSONStatus(w, &HTTPError{Message: fmt.Sprintf("wait must be a positive duration of at most %s", maxWait)}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var messages []model.Message
+	var err error
+	if wait > 0 {
+		messages, err = h.service.WaitForNewMessages(ctx, recipientUserName, wait)
+	} else {
+		messages, err = h.service.FetchNewMessages(ctx, recipientUserName)
+	}
 	if err != nil {
+		if ctx.Err() != nil {
+			// The client gave up waiting.
+			return
+		}
 		h.logger.Error("error fetching new messages", zap.Error(err))
 		respondJSONStatus(w, &HTTPError{Message: "error fetching new messages"}, http.StatusInternalServerError)
 		return
@@ -18,6 +18,38 @@ import (
 )
 
 func TestHandler_GetNewMessages(t *testing.T) {
+	t.Run("should forward recipient user name to service", func(t *testing.T) {
+		wantRecipientUserName := "recipient"
+
+		gotFetchNewMessagesCalled := false
+		service := &mock.Service{
+			FetchNewMessagesFunc: func(ctx context.Context, recipientUserName string) ([]model.Message, error) {
+				gotFetchNewMessagesCalled = true
+				if got, want := recipientUserName, wantRecipientUserName; got != want {
+					t.Errorf("got recipient user name %q, want %q", got, want)
+				}
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/users/%s/messages/new", testServer.URL, wantRecipientUserName)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		wantFetchNewMessagesCalled := true
+		if got, want := gotFetchNewMessagesCalled, wantFetchNewMessagesCalled; got != want {
+			t.Errorf("got fetch new messages called %t, want %t", got, want)
+		}
+	})
+
 	t.Run("should return messages from service", func(t *testing.T) {
 		wantMessages := []model.Message{
 			{
@@ -35,14 +67,14 @@ func TestHandler_GetNewMessages(t *testing.T) {
 		}
 
 		service := &mock.Service{
-			FetchNewMessagesFunc: func(ctx context.Context) ([]model.Message, error) {
+			FetchNewMessagesFunc: func(ctx context.Context, recipientUserName string) ([]model.Message, error) {
 				return wantMessages, nil
 			},
 		}
 
 		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
 
-		url := fmt.Sprintf("%s/messages/new", testServer.URL)
+		url := fmt.Sprintf("%s/users/recipient/messages/new", testServer.URL)
 		resp, err := testServer.Client().Get(url)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -64,14 +96,14 @@ func TestHandler_GetNewMessages(t *testing.T) {
 
 	t.Run("should return empty array if no messages are available", func(t *testing.T) {
 		service := &mock.Service{
-			FetchNewMessagesFunc: func(ctx context.Context) ([]model.Message, error) {
+			FetchNewMessagesFunc: func(ctx context.Context, recipientUserName string) ([]model.Message, error) {
 				return nil, nil
 			},
 		}
 
 		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
 
-		url := fmt.Sprintf("%s/messages/new", testServer.URL)
+		url := fmt.Sprintf("%s/users/recipient/messages/new", testServer.URL)
 		resp, err := testServer.Client().Get(url)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -92,16 +124,79 @@ func TestHandler_GetNewMessages(t *testing.T) {
 		}
 	})
 
+	t.Run("should wait for new messages if wait is given", func(t *testing.T) {
+		wantWait := 30 * time.Second
+
+		gotWaitForNewMessagesCalled := false
+		service := &mock.Service{
+			WaitForNewMessagesFunc: func(ctx context.Context, recipientUserName string, wait time.Duration) ([]model.Message, error) {
+				gotWaitForNewMessagesCalled = true
+				if got, want := recipientUserName, "recipient"; got != want {
+					t.Errorf("got recipient user name %q, want %q", got, want)
+				}
+				if got, want := wait, wantWait; got != want {
+					t.Errorf("got wait %v, want %v", got, want)
+				}
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/users/recipient/messages/new?wait=30s", testServer.URL)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		wantWaitForNewMessagesCalled := true
+		if got, want := gotWaitForNewMessagesCalled, wantWaitForNewMessagesCalled; got != want {
+			t.Errorf("got wait for new messages called %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should return 400 if wait is invalid", func(t *testing.T) {
+		for _, wait := range []string{"soon", "0s", "-1s", "2m"} {
+			service := &mock.Service{}
+
+			testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+			url := fmt.Sprintf("%s/users/recipient/messages/new?wait=%s", testServer.URL, wait)
+			resp, err := testServer.Client().Get(url)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+				t.Errorf("got HTTP status %d for wait %q, want %d", got, wait, want)
+			}
+
+			respBody, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			wantRespBody := "{\"message\":\"wait must be a positive duration of at most 1m0s\"}\n"
+			if got, want := string(respBody), wantRespBody; got != want {
+				t.Errorf("got response body %q, want %q", got, want)
+			}
+		}
+	})
+
 	t.Run("should return 500 if service returns error", func(t *testing.T) {
 		service := &mock.Service{
-			FetchNewMessagesFunc: func(ctx context.Context) ([]model.Message, error) {
+			FetchNewMessagesFunc: func(ctx context.Context, recipientUserName string) ([]model.Message, error) {
 				return nil, fmt.Errorf("service error")
 			},
 		}
 
 		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
 
-		url := fmt.Sprintf("%s/messages/new", testServer.URL)
+		url := fmt.Sprintf("%s/users/recipient/messages/new", testServer.URL)
 		resp, err := testServer.Client().Get(url)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func (h *handler) getSentMessages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	senderUserName := chi.URLParam(r, "user_name")
+	if senderUserName == "" {
+		respondJSONStatus(w, &HTTPError{Message: "user_name is required"}, http.StatusBadRequest)
+		return
+	}
+
+	if userName := core.RestrictedUserName(ctx); userName != nil && *userName != senderUserName {
+		respondJSONStatus(w, &HTTPError{Message: "cannot get sent messages of another user"}, http.StatusForbidden)
+		return
+	}
+
+	messages, err := h.service.GetSentMessages(ctx, senderUserName)
+	if err != nil {
+		h.logger.Error("error fetching sent messages", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error fetching sent messages"}, http.StatusInternalServerError)
+		return
+	}
+
+	if messages == nil {
+		messages = []model.Message{}
+	}
+
+	respondJSONStatus(w, &messages, http.StatusOK)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+func (h *handler) getTrash(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	recipientUserNameRaw := r.URL.Query().Get("user_name")
+	var recipientUserName *string
+	if recipientUserNameRaw != "" {
+		recipientUserName = &recipientUserNameRaw
+	}
+
+	if userName := core.RestrictedUserName(ctx); userName != nil {
+		if recipientUserName != nil && *recipientUserName != *userName {
+			respondJSONStatus(w, &HTTPError{Message: "cannot get trash of another user"}, http.StatusForbidden)
+			return
+		}
+		recipientUserName = userName
+	}
+
+	messages, err := h.service.GetTrash(ctx, recipientUserName)
+	if err != nil {
+		h.logger.Error("error fetching trash", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error fetching trash"}, http.StatusInternalServerError)
+		return
+	}
+
+	if messages == nil {
+		messages = []model.Message{}
+	}
+
+	respondJSONStatus(w, &messages, http.StatusOK)
+}
@@ -0,0 +1,152 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestHandler_GetTrash(t *testing.T) {
+	t.Run("should forward user name to service", func(t *testing.T) {
+		wantRecipientUserName := "recipient"
+
+		gotGetTrashCalled := false
+		service := &mock.Service{
+			GetTrashFunc: func(ctx context.Context, recipientUserName *string) ([]model.Message, error) {
+				gotGetTrashCalled = true
+				if diff := cmp.Diff(&wantRecipientUserName, recipientUserName); diff != "" {
+					t.Errorf("recipient user name mismatch (-want +got):\n%s", diff)
+				}
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/trash?user_name=%s", testServer.URL, wantRecipientUserName)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		wantGetTrashCalled := true
+		if got, want := gotGetTrashCalled, wantGetTrashCalled; got != want {
+			t.Errorf("got GetTrash called %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should return deleted messages from service", func(t *testing.T) {
+		deletedAt := time.Now()
+		wantMessages := []model.Message{
+			{
+				ID:                "id-1",
+				RecipientUserName: "recipient-1",
+				Content:           "content-1",
+				SentAt:            time.Now(),
+				DeletedAt:         &deletedAt,
+			},
+		}
+
+		service := &mock.Service{
+			GetTrashFunc: func(ctx context.Context, recipientUserName *string) ([]model.Message, error) {
+				return wantMessages, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/trash", testServer.URL)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		var gotMessages []model.Message
+		if err := json.NewDecoder(resp.Body).Decode(&gotMessages); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(gotMessages, wantMessages); diff != "" {
+			t.Errorf("messages mismatch (-got +want): %s", diff)
+		}
+	})
+
+	t.Run("should return empty array if the trash is empty", func(t *testing.T) {
+		service := &mock.Service{
+			GetTrashFunc: func(ctx context.Context, recipientUserName *string) ([]model.Message, error) {
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/trash", testServer.URL)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "[]\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns error", func(t *testing.T) {
+		service := &mock.Service{
+			GetTrashFunc: func(ctx context.Context, recipientUserName *string) ([]model.Message, error) {
+				return nil, fmt.Errorf("service error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/trash", testServer.URL)
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"error fetching trash\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func (h *handler) getUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	recipientUserName := chi.URLParam(r, "user_name")
+	if recipientUserName == "" {
+		respondJSONStatus(w, &HTTPError{Message: "user_name is required"}, http.StatusBadRequest)
+		return
+	}
+
+	if user, ok := core.UserFromContext(ctx); ok && !user.Admin && user.Name != recipientUserName {
+		respondJSONStatus(w, &HTTPError{Message: "cannot get usage of another user"}, http.StatusForbidden)
+		return
+	}
+
+	usage, err := h.service.GetUsage(ctx, recipientUserName)
+	if err != nil {
+		h.logger.Error("error getting usage", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error getting usage"}, http.StatusInternalServerError)
+		return
+	}
+
+	respondJSONStatus(w, &usage, http.StatusOK)
+}
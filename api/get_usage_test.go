@@ -0,0 +1,102 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestHandler_GetUsage(t *testing.T) {
+	t.Run("should return usage from service", func(t *testing.T) {
+		wantUsage := model.Usage{
+			UserName:          "user",
+			UnfetchedMessages: 3,
+			Bytes:             21,
+			Quota:             model.Quota{MaxUnfetchedMessages: 10, MaxBytes: 1000},
+		}
+
+		service := &mock.Service{
+			GetUsageFunc: func(ctx context.Context, recipientUserName string) (model.Usage, error) {
+				if got, want := recipientUserName, "user"; got != want {
+					t.Errorf("got recipient user name %q, want %q", got, want)
+				}
+				return wantUsage, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/users/user/usage", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		var gotUsage model.Usage
+		if err := json.NewDecoder(resp.Body).Decode(&gotUsage); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantUsage, gotUsage); diff != "" {
+			t.Errorf("usage mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return 403 if usage of another user is requested", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterAuthenticator(newAuthenticator())))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/users/other-user/usage", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns error", func(t *testing.T) {
+		service := &mock.Service{
+			GetUsageFunc: func(ctx context.Context, recipientUserName string) (model.Usage, error) {
+				return model.Usage{}, fmt.Errorf("service error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/users/user/usage", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"error getting usage\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+}
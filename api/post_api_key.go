@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+func (h *handler) postAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqBody := struct {
+		UserName string   `json:"user_name"`
+		Scopes   []string `json:"scopes"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondJSONStatus(w, &HTTPError{Message: fmt.Sprintf("decode request body: %v", err)}, http.StatusBadRequest)
+		return
+	}
+
+	if reqBody.UserName == "" {
+		respondJSONStatus(w, &HTTPError{Message: "user_name is required"}, http.StatusBadRequest)
+		return
+	}
+
+	if len(reqBody.Scopes) == 0 {
+		respondJSONStatus(w, &HTTPError{Message: "scopes is required"}, http.StatusBadRequest)
+		return
+	}
+
+	for _, scope := range reqBody.Scopes {
+		if !isKnownScope(scope) {
+			respondJSONStatus(w, &HTTPError{Message: fmt.Sprintf("unknown scope %s", scope)}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	apiKey, err := h.service.CreateAPIKey(ctx, reqBody.UserName, reqBody.Scopes)
+	if err != nil {
+		h.logger.Error("error creating api key", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error creating API key"}, http.StatusInternalServerError)
+		return
+	}
+
+	respondJSONStatus(w, &apiKey, http.StatusCreated)
+}
+
+func isKnownScope(scope string) bool {
+	for _, known := range model.Scopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
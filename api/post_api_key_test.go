@@ -0,0 +1,138 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestHandler_PostAPIKey(t *testing.T) {
+	t.Run("should return created API key", func(t *testing.T) {
+		wantAPIKey := model.IssuedAPIKey{
+			APIKey: model.APIKey{
+				ID:        "api-key-id",
+				UserName:  "user",
+				Scopes:    []string{model.ScopeMessagesSend},
+				CreatedAt: time.Now(),
+			},
+			Key: "msk_key",
+		}
+
+		service := &mock.Service{
+			CreateAPIKeyFunc: func(ctx context.Context, userName string, scopes []string) (model.IssuedAPIKey, error) {
+				if got, want := userName, "user"; got != want {
+					t.Errorf("got user name %q, want %q", got, want)
+				}
+				if diff := cmp.Diff([]string{model.ScopeMessagesSend}, scopes); diff != "" {
+					t.Errorf("scopes mismatch (-want +got):\n%s", diff)
+				}
+				return wantAPIKey, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/api-keys", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(`{"user_name": "user", "scopes": ["messages:send"]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusCreated; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		var gotAPIKey model.IssuedAPIKey
+		if err := json.NewDecoder(resp.Body).Decode(&gotAPIKey); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantAPIKey, gotAPIKey); diff != "" {
+			t.Errorf("api key mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return 400 if user name is missing", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/api-keys", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(`{"scopes": ["messages:send"]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"user_name is required\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 400 if a scope is unknown", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/api-keys", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(`{"user_name": "user", "scopes": ["messages:write"]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"unknown scope messages:write\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns error", func(t *testing.T) {
+		service := &mock.Service{
+			CreateAPIKeyFunc: func(ctx context.Context, userName string, scopes []string) (model.IssuedAPIKey, error) {
+				return model.IssuedAPIKey{}, fmt.Errorf("service error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/api-keys", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(`{"user_name": "user", "scopes": ["messages:send"]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
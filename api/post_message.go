@@ -1,33 +1,106 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
 	"go.uber.org/zap"
 )
 
+type messageRequest struct {
+	SenderUserName    string     `json:"sender_user_name"`
+	RecipientUserName string     `json:"recipient_user_name"`
+	Content           string     `json:"content"`
+	DeliverAt         *time.Time `json:"deliver_at"`
+	TTLSeconds        *int       `json:"ttl_seconds"`
+}
+
+// validate returns why the message request is invalid, or an empty string if
+// it is valid.
+func (m messageRequest) validate() string {
+	if m.SenderUserName == "" {
+		return "sender_user_name is required"
+	}
+
+	if m.RecipientUserName == "" {
+		return "user_name is required"
+	}
+
+	if m.TTLSeconds != nil && *m.TTLSeconds <= 0 {
+		return "ttl_seconds must be positive"
+	}
+
+	return ""
+}
+
+// withAuthenticatedSender returns the message request sent in the name of the
+// authenticated user, if there is one. ok is false if the request names another
+// sender.
+func (m messageRequest) withAuthenticatedSender(ctx context.Context) (_ messageRequest, ok bool) {
+	user, authenticated := core.UserFromContext(ctx)
+	if !authenticated {
+		return m, true
+	}
+
+	if m.SenderUserName != "" && m.SenderUserName != user.Name {
+		return m, false
+	}
+
+	m.SenderUserName = user.Name
+	return m, true
+}
+
+func (m messageRequest) submission() model.MessageSubmission {
+	submission := model.MessageSubmission{
+		SenderUserName:    m.SenderUserName,
+		RecipientUserName: m.RecipientUserName,
+		Content:           m.Content,
+		DeliverAt:         m.DeliverAt,
+	}
+
+	if m.TTLSeconds != nil {
+		submission.TTL = time.Duration(*m.TTLSeconds) * time.Second
+	}
+
+	return submission
+}
+
 func (h *handler) postMessage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	reqBody := struct {
-		RecipientUserName string `json:"recipient_user_name"`
-		Content           string `json:"content"`
-	}{}
-
+	var reqBody messageRequest
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
 		respondJSONStatus(w, &HTTPError{Message: fmt.Sprintf("decode request body: %v", err)}, http.StatusBadRequest)
 		return
 	}
 
-	if reqBody.RecipientUserName == "" {
-		respondJSONStatus(w, &HTTPError{Message: "user_name is required"}, http.StatusBadRequest)
+	reqBody, ok := reqBody.withAuthenticatedSender(ctx)
+	if !ok {
+		respondJSONStatus(w, &HTTPError{Message: "cannot send messages in the name of another user"}, http.StatusForbidden)
 		return
 	}
 
-	messageID, err := h.service.SubmitMessage(ctx, reqBody.RecipientUserName, reqBody.Content)
+	if msg := reqBody.validate(); msg != "" {
+		respondJSONStatus(w, &HTTPError{Message: msg}, http.StatusBadRequest)
+		return
+	}
+
+	submission := reqBody.submission()
+	submission.IdempotencyKey = r.Header.Get("Idempotency-Key")
+
+	messageID, err := h.service.SubmitMessage(ctx, submission)
 	if err != nil {
+		var quotaErr *model.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			respondQuotaExceeded(w, quotaErr)
+			return
+		}
 		h.logger.Error("error submitting message", zap.Error(err))
 		respondJSONStatus(w, &HTTPError{Message: "error submitting message"}, http.StatusInternalServerError)
 		return
@@ -41,3 +114,17 @@ func (h *handler) postMessage(w http.ResponseWriter, r *http.Request) {
 
 	respondJSONStatus(w, &respBody, http.StatusOK)
 }
+
+type quotaExceededHTTPError struct {
+	HTTPError
+	Quota string `json:"quota"`
+}
+
+// respondQuotaExceeded tells the sender that the mailbox of the recipient is
+// full until the recipient fetches or deletes messages.
+func respondQuotaExceeded(w http.ResponseWriter, err *model.QuotaExceededError) {
+	respondJSONStatus(w, &quotaExceededHTTPError{
+		HTTPError: HTTPError{Message: fmt.Sprintf("mailbox of recipient %s is full", err.UserName)},
+		Quota:     err.Quota,
+	}, http.StatusInsufficientStorage)
+}
@@ -9,24 +9,30 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/RichterMaximilian/osttra-coding-assignment/api"
 	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
 	"go.uber.org/zap"
 )
 
 func TestHandler_PostMessage(t *testing.T) {
 	t.Run("should forward message to service", func(t *testing.T) {
-		wantRecipientUserName, wantContent := "recipient", "content"
+		wantSenderUserName, wantRecipientUserName, wantContent := "sender", "recipient", "content"
 
 		gotSubmitMessageCalled := false
 		service := &mock.Service{
-			SubmitMessageFunc: func(ctx context.Context, recipientUserName, content string) (string, error) {
+			SubmitMessageFunc: func(ctx context.Context, submission model.MessageSubmission) (string, error) {
 				gotSubmitMessageCalled = true
-				if got, want := recipientUserName, wantRecipientUserName; got != want {
+				if got, want := submission.SenderUserName, wantSenderUserName; got != want {
+					t.Errorf("got sender user name %q, want %q", got, want)
+				}
+				if got, want := submission.RecipientUserName, wantRecipientUserName; got != want {
 					t.Errorf("got recipient user name %q, want %q", got, want)
 				}
-				if got, want := content, wantContent; got != want {
+				if got, want := submission.Content, wantContent; got != want {
 					t.Errorf("got content %q, want %q", got, want)
 				}
 				return "", nil
@@ -36,7 +42,111 @@ func TestHandler_PostMessage(t *testing.T) {
 		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
 
 		url := fmt.Sprintf("%s/messages", testServer.URL)
-		reqBody := fmt.Sprintf(`{"recipient_user_name": "%s", "content": "%s"}`, wantRecipientUserName, wantContent)
+		reqBody := fmt.Sprintf(`{"sender_user_name": "%s", "recipient_user_name": "%s", "content": "%s"}`, wantSenderUserName, wantRecipientUserName, wantContent)
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		wantSubmitMessageCalled := true
+		if got, want := gotSubmitMessageCalled, wantSubmitMessageCalled; got != want {
+			t.Errorf("got submit message called %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should forward idempotency key to service", func(t *testing.T) {
+		wantIdempotencyKey := "idempotency-key"
+
+		gotSubmitMessageCalled := false
+		service := &mock.Service{
+			SubmitMessageFunc: func(ctx context.Context, submission model.MessageSubmission) (string, error) {
+				gotSubmitMessageCalled = true
+				if got, want := submission.IdempotencyKey, wantIdempotencyKey; got != want {
+					t.Errorf("got idempotency key %q, want %q", got, want)
+				}
+				return "", nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages", testServer.URL)
+		reqBody := `{"sender_user_name": "sender", "recipient_user_name": "recipient", "content": "content"}`
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("Idempotency-Key", wantIdempotencyKey)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		wantSubmitMessageCalled := true
+		if got, want := gotSubmitMessageCalled, wantSubmitMessageCalled; got != want {
+			t.Errorf("got submit message called %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should forward delivery time to service", func(t *testing.T) {
+		wantDeliverAt := time.Date(2023, 4, 13, 19, 43, 23, 0, time.UTC)
+
+		gotSubmitMessageCalled := false
+		service := &mock.Service{
+			SubmitMessageFunc: func(ctx context.Context, submission model.MessageSubmission) (string, error) {
+				gotSubmitMessageCalled = true
+				if diff := cmp.Diff(&wantDeliverAt, submission.DeliverAt); diff != "" {
+					t.Errorf("deliver at mismatch (-want +got):\n%s", diff)
+				}
+				return "", nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages", testServer.URL)
+		reqBody := `{"sender_user_name": "sender", "recipient_user_name": "recipient", "content": "content", "deliver_at": "2023-04-13T19:43:23Z"}`
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		wantSubmitMessageCalled := true
+		if got, want := gotSubmitMessageCalled, wantSubmitMessageCalled; got != want {
+			t.Errorf("got submit message called %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should forward ttl to service", func(t *testing.T) {
+		wantTTL := 90 * time.Second
+
+		gotSubmitMessageCalled := false
+		service := &mock.Service{
+			SubmitMessageFunc: func(ctx context.Context, submission model.MessageSubmission) (string, error) {
+				gotSubmitMessageCalled = true
+				if got, want := submission.TTL, wantTTL; got != want {
+					t.Errorf("got ttl %v, want %v", got, want)
+				}
+				return "", nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages", testServer.URL)
+		reqBody := `{"sender_user_name": "sender", "recipient_user_name": "recipient", "content": "content", "ttl_seconds": 90}`
 		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -56,7 +166,7 @@ func TestHandler_PostMessage(t *testing.T) {
 		wantMessageID := "message-id"
 
 		service := &mock.Service{
-			SubmitMessageFunc: func(ctx context.Context, recipientUserName, content string) (string, error) {
+			SubmitMessageFunc: func(ctx context.Context, submission model.MessageSubmission) (string, error) {
 				return wantMessageID, nil
 			},
 		}
@@ -64,7 +174,7 @@ func TestHandler_PostMessage(t *testing.T) {
 		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
 
 		url := fmt.Sprintf("%s/messages", testServer.URL)
-		reqBody := `{"recipient_user_name": "recipient", "content": "content"}`
+		reqBody := `{"sender_user_name": "sender", "recipient_user_name": "recipient", "content": "content"}`
 		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -111,13 +221,40 @@ func TestHandler_PostMessage(t *testing.T) {
 		}
 	})
 
+	t.Run("should return 400 if sender user name is missing", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages", testServer.URL)
+		reqBody := `{"recipient_user_name": "recipient", "content": "content"}`
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"sender_user_name is required\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
 	t.Run("should return 400 if recipient user name is missing", func(t *testing.T) {
 		service := &mock.Service{}
 
 		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
 
 		url := fmt.Sprintf("%s/messages", testServer.URL)
-		reqBody := `{"content": "content"}`
+		reqBody := `{"sender_user_name": "sender", "content": "content"}`
 		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -138,9 +275,36 @@ func TestHandler_PostMessage(t *testing.T) {
 		}
 	})
 
+	t.Run("should return 400 if ttl is not positive", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages", testServer.URL)
+		reqBody := `{"sender_user_name": "sender", "recipient_user_name": "recipient", "content": "content", "ttl_seconds": 0}`
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"ttl_seconds must be positive\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
 	t.Run("should return 500 if service returns error", func(t *testing.T) {
 		service := &mock.Service{
-			SubmitMessageFunc: func(ctx context.Context, recipientUserName, content string) (string, error) {
+			SubmitMessageFunc: func(ctx context.Context, submission model.MessageSubmission) (string, error) {
 				return "", errors.New("service error")
 			},
 		}
@@ -148,7 +312,7 @@ func TestHandler_PostMessage(t *testing.T) {
 		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
 
 		url := fmt.Sprintf("%s/messages", testServer.URL)
-		reqBody := `{"recipient_user_name": "recipient", "content": "content"}`
+		reqBody := `{"sender_user_name": "sender", "recipient_user_name": "recipient", "content": "content"}`
 		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -168,4 +332,34 @@ func TestHandler_PostMessage(t *testing.T) {
 			t.Errorf("got response body %q, want %q", got, want)
 		}
 	})
+	t.Run("should return 507 if the mailbox of the recipient is full", func(t *testing.T) {
+		service := &mock.Service{
+			SubmitMessageFunc: func(ctx context.Context, submission model.MessageSubmission) (string, error) {
+				return "", &model.QuotaExceededError{UserName: "recipient", Quota: model.QuotaBytes}
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages", testServer.URL)
+		reqBody := `{"sender_user_name": "sender", "recipient_user_name": "recipient", "content": "content"}`
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInsufficientStorage; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"mailbox of recipient recipient is full\",\"quota\":\"bytes\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
 }
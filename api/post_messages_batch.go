@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+const maxBatchSize = 1000
+
+type batchItemResult struct {
+	MessageID string `json:"message_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (h *handler) postMessagesBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var reqBody []messageRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondJSONStatus(w, &HTTPError{Message: fmt.Sprintf("decode request body: %v", err)}, http.StatusBadRequest)
+		return
+	}
+
+	if len(reqBody) == 0 {
+		respondJSONStatus(w, &HTTPError{Message: "messages are required"}, http.StatusBadRequest)
+		return
+	}
+
+	if len(reqBody) > maxBatchSize {
+		respondJSONStatus(w, &HTTPError{Message: fmt.Sprintf("at most %d messages can be submitted at once", maxBatchSize)}, http.StatusBadRequest)
+		return
+	}
+
+	for i, item := range reqBody {
+		var ok bool
+		if reqBody[i], ok = item.withAuthenticatedSender(ctx); !ok {
+			respondJSONStatus(w, &HTTPError{Message: "cannot send messages in the name of another user"}, http.StatusForbidden)
+			return
+		}
+	}
+
+	results := make([]batchItemResult, len(reqBody))
+	submissions := make([]model.MessageSubmission, 0, len(reqBody))
+	// submissionIdx maps the submissions to their position in the request.
+	submissionIdx := make([]int, 0, len(reqBody))
+	for i, item := range reqBody {
+		if msg := item.validate(); msg != "" {
+			results[i].Error = msg
+			continue
+		}
+		submissions = append(submissions, item.submission())
+		submissionIdx = append(submissionIdx, i)
+	}
+
+	if len(submissions) > 0 {
+		messageIDs, err := h.service.SubmitMessages(ctx, submissions)
+		if err != nil {
+			var quotaErr *model.QuotaExceededError
+			if errors.As(err, &quotaErr) {
+				respondQuotaExceeded(w, quotaErr)
+				return
+			}
+			h.logger.Error("error submitting messages", zap.Error(err))
+			respondJSONStatus(w, &HTTPError{Message: "error submitting messages"}, http.StatusInternalServerError)
+			return
+		}
+
+		for i, messageID := range messageIDs {
+			results[submissionIdx[i]].MessageID = messageID
+		}
+	}
+
+	status := http.StatusOK
+	if len(submissions) != len(reqBody) {
+		status = http.StatusMultiStatus
+	}
+
+	respondJSONStatus(w, &results, status)
+}
@@ -0,0 +1,210 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestHandler_PostMessagesBatch(t *testing.T) {
+	t.Run("should forward messages to service and return their ids", func(t *testing.T) {
+		wantSubmissions := []model.MessageSubmission{
+			{
+				SenderUserName:    "sender",
+				RecipientUserName: "recipient-1",
+				Content:           "content-1",
+			},
+			{
+				SenderUserName:    "sender",
+				RecipientUserName: "recipient-2",
+				Content:           "content-2",
+			},
+		}
+
+		service := &mock.Service{
+			SubmitMessagesFunc: func(ctx context.Context, submissions []model.MessageSubmission) ([]string, error) {
+				if diff := cmp.Diff(wantSubmissions, submissions); diff != "" {
+					t.Errorf("submissions mismatch (-want +got):\n%s", diff)
+				}
+				return []string{"message-id-1", "message-id-2"}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/batch", testServer.URL)
+		reqBody := `[
+			{"sender_user_name": "sender", "recipient_user_name": "recipient-1", "content": "content-1"},
+			{"sender_user_name": "sender", "recipient_user_name": "recipient-2", "content": "content-2"}
+		]`
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "[{\"message_id\":\"message-id-1\"},{\"message_id\":\"message-id-2\"}]\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 207 with per item errors if some messages are invalid", func(t *testing.T) {
+		service := &mock.Service{
+			SubmitMessagesFunc: func(ctx context.Context, submissions []model.MessageSubmission) ([]string, error) {
+				if got, want := len(submissions), 1; got != want {
+					t.Errorf("got %d submissions, want %d", got, want)
+				}
+				return []string{"message-id-2"}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/batch", testServer.URL)
+		reqBody := `[
+			{"sender_user_name": "sender", "content": "content-1"},
+			{"sender_user_name": "sender", "recipient_user_name": "recipient-2", "content": "content-2"}
+		]`
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusMultiStatus; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "[{\"error\":\"user_name is required\"},{\"message_id\":\"message-id-2\"}]\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 400 if request body is invalid", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/batch", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader("["))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"decode request body: unexpected EOF\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 400 if no messages are provided", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/batch", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader("[]"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"messages are required\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns error", func(t *testing.T) {
+		service := &mock.Service{
+			SubmitMessagesFunc: func(ctx context.Context, submissions []model.MessageSubmission) ([]string, error) {
+				return nil, errors.New("service error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/batch", testServer.URL)
+		reqBody := `[{"sender_user_name": "sender", "recipient_user_name": "recipient", "content": "content"}]`
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"error submitting messages\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+	t.Run("should return 507 if the mailbox of a recipient is full", func(t *testing.T) {
+		service := &mock.Service{
+			SubmitMessagesFunc: func(ctx context.Context, submissions []model.MessageSubmission) ([]string, error) {
+				return nil, &model.QuotaExceededError{UserName: "recipient", Quota: model.QuotaUnfetchedMessages}
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		url := fmt.Sprintf("%s/messages/batch", testServer.URL)
+		reqBody := `[{"sender_user_name": "sender", "recipient_user_name": "recipient", "content": "content"}]`
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInsufficientStorage; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
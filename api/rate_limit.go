@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type RateLimiter interface {
+	Take(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitResult, error)
+}
+
+// RateLimits are the limits of the routes. Routes are identified by their
+// method and pattern, e.g. "POST /messages", and routes without a limit of their
+// own get the default limit. IP limits the requests of each IP address to all
+// routes together, which are counted before authentication, so that requests
+// with invalid credentials are limited as well. A limit of zero disables rate
+// limiting.
+type RateLimits struct {
+	Default model.RateLimit
+	Routes  map[string]model.RateLimit
+	IP      model.RateLimit
+}
+
+// RouterRateLimiter limits the requests every client can make to each route.
+// Clients are identified by their API key, their user name or, if they are not
+// authenticated, their IP address.
+func RouterRateLimiter(rateLimiter RateLimiter, rateLimits RateLimits) routerOptsFunc {
+	return func(h *handler) {
+		h.rateLimiter = rateLimiter
+		h.rateLimits = rateLimits
+	}
+}
+
+// rateLimitIP rejects requests of IP addresses that exceeded the IP limit. It
+// runs before authentication, so that clients cannot guess credentials without
+// limit.
+func (h *handler) rateLimitIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.rateLimiter == nil || h.rateLimits.IP.Limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !h.takeRateLimit(w, r, "ip:"+remoteHost(r), h.rateLimits.IP) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimit rejects requests of clients that exceeded the limit of the route.
+// It needs to run after routing, as the limit depends on the route pattern.
+func (h *handler) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.rateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := r.Method + " " + chi.RouteContext(r.Context()).RoutePattern()
+		limit, ok := h.rateLimits.Routes[route]
+		if !ok {
+			limit = h.rateLimits.Default
+		}
+		if limit.Limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !h.takeRateLimit(w, r, rateLimitClient(r)+" "+route, limit) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// takeRateLimit takes a token from the bucket of the key and reports whether
+// the request may be served. If not, it has already been answered.
+func (h *handler) takeRateLimit(w http.ResponseWriter, r *http.Request, key string, limit model.RateLimit) bool {
+	result, err := h.rateLimiter.Take(r.Context(), key, limit)
+	if err != nil {
+		// Serving requests without a limit beats failing all of them while
+		// the shared counter is unavailable.
+		h.logger.Error("error taking rate limit token", zap.Error(err))
+		return true
+	}
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", formatSeconds(result.Reset))
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", formatSeconds(result.RetryAfter))
+		respondJSONStatus(w, &HTTPError{Message: "rate limit exceeded"}, http.StatusTooManyRequests)
+		return false
+	}
+
+	return true
+}
+
+// rateLimitClient identifies the client whose requests are counted together.
+func rateLimitClient(r *http.Request) string {
+	if user, ok := core.UserFromContext(r.Context()); ok {
+		if user.APIKeyID != "" {
+			return "api-key:" + user.APIKeyID
+		}
+		return "user:" + user.Name
+	}
+
+	return "ip:" + remoteHost(r)
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// formatSeconds rounds up, so that clients do not retry too early.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,233 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestHandler_RateLimit(t *testing.T) {
+	t.Run("should take a token of the route limit for the client", func(t *testing.T) {
+		wantLimit := model.RateLimit{Limit: 10, Period: time.Minute}
+
+		gotTakeCalled := false
+		rateLimiter := &mock.RateLimiter{
+			TakeFunc: func(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitResult, error) {
+				gotTakeCalled = true
+				if got, want := key, "user:user GET /messages/{message_id}"; got != want {
+					t.Errorf("got key %q, want %q", got, want)
+				}
+				if diff := cmp.Diff(wantLimit, limit); diff != "" {
+					t.Errorf("limit mismatch (-want +got):\n%s", diff)
+				}
+				return model.RateLimitResult{Allowed: true, Remaining: 9, Reset: 6 * time.Second}, nil
+			},
+		}
+		service := &mock.Service{
+			GetMessageFunc: func(ctx context.Context, messageID string) (model.Message, error) {
+				return model.Message{ID: messageID, RecipientUserName: "user"}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(
+			service,
+			zap.NewNop(),
+			api.RouterAuthenticator(newAuthenticator()),
+			api.RouterRateLimiter(rateLimiter, api.RateLimits{
+				Default: model.RateLimit{Limit: 100, Period: time.Minute},
+				Routes:  map[string]model.RateLimit{"GET /messages/{message_id}": wantLimit},
+			}),
+		))
+
+		req := newAuthenticatedRequest(t, http.MethodGet, fmt.Sprintf("%s/messages/message-id", testServer.URL), nil)
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if !gotTakeCalled {
+			t.Error("take should have been called")
+		}
+
+		if got, want := resp.Header.Get("RateLimit-Limit"), "10"; got != want {
+			t.Errorf("got RateLimit-Limit header %q, want %q", got, want)
+		}
+		if got, want := resp.Header.Get("RateLimit-Remaining"), "9"; got != want {
+			t.Errorf("got RateLimit-Remaining header %q, want %q", got, want)
+		}
+		if got, want := resp.Header.Get("RateLimit-Reset"), "6"; got != want {
+			t.Errorf("got RateLimit-Reset header %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should use the default limit for other routes", func(t *testing.T) {
+		wantLimit := model.RateLimit{Limit: 100, Period: time.Minute}
+
+		rateLimiter := &mock.RateLimiter{
+			TakeFunc: func(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitResult, error) {
+				if !strings.HasPrefix(key, "ip:") {
+					t.Errorf("got key %q, want prefix %q", key, "ip:")
+				}
+				if diff := cmp.Diff(wantLimit, limit); diff != "" {
+					t.Errorf("limit mismatch (-want +got):\n%s", diff)
+				}
+				return model.RateLimitResult{Allowed: true}, nil
+			},
+		}
+		service := &mock.Service{
+			SubmitMessageFunc: func(ctx context.Context, submission model.MessageSubmission) (string, error) {
+				return "message-id", nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterRateLimiter(rateLimiter, api.RateLimits{Default: wantLimit})))
+
+		url := fmt.Sprintf("%s/messages", testServer.URL)
+		resp, err := testServer.Client().Post(url, "application/json", strings.NewReader(`{"sender_user_name": "sender", "recipient_user_name": "recipient", "content": "content"}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should return 429 if the limit is exceeded", func(t *testing.T) {
+		rateLimiter := &mock.RateLimiter{
+			TakeFunc: func(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitResult, error) {
+				return model.RateLimitResult{Allowed: false, RetryAfter: 1500 * time.Millisecond, Reset: time.Minute}, nil
+			},
+		}
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterRateLimiter(rateLimiter, api.RateLimits{
+			Default: model.RateLimit{Limit: 100, Period: time.Minute},
+		})))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusTooManyRequests; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if got, want := resp.Header.Get("Retry-After"), "2"; got != want {
+			t.Errorf("got Retry-After header %q, want %q", got, want)
+		}
+		if got, want := resp.Header.Get("RateLimit-Remaining"), "0"; got != want {
+			t.Errorf("got RateLimit-Remaining header %q, want %q", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"rate limit exceeded\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should not limit routes with a limit of zero", func(t *testing.T) {
+		rateLimiter := &mock.RateLimiter{}
+		service := &mock.Service{
+			GetDeadLettersFunc: func(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterRateLimiter(rateLimiter, api.RateLimits{})))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/dead-letters", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should serve requests if the rate limiter fails", func(t *testing.T) {
+		rateLimiter := &mock.RateLimiter{
+			TakeFunc: func(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitResult, error) {
+				return model.RateLimitResult{}, errors.New("some error")
+			},
+		}
+		service := &mock.Service{
+			GetDeadLettersFunc: func(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+				return nil, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop(), api.RouterRateLimiter(rateLimiter, api.RateLimits{
+			Default: model.RateLimit{Limit: 100, Period: time.Minute},
+		})))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/dead-letters", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+	t.Run("should limit requests with invalid credentials by IP address", func(t *testing.T) {
+		wantLimit := model.RateLimit{Limit: 20, Period: time.Minute}
+
+		rateLimiter := &mock.RateLimiter{
+			TakeFunc: func(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitResult, error) {
+				if got, want := key, "ip:127.0.0.1"; got != want {
+					t.Errorf("got key %q, want %q", got, want)
+				}
+				if diff := cmp.Diff(wantLimit, limit); diff != "" {
+					t.Errorf("limit mismatch (-want +got):\n%s", diff)
+				}
+				return model.RateLimitResult{Allowed: false, RetryAfter: time.Second, Reset: time.Minute}, nil
+			},
+		}
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(
+			service,
+			zap.NewNop(),
+			api.RouterAuthenticator(newAuthenticator()),
+			api.RouterRateLimiter(rateLimiter, api.RateLimits{IP: wantLimit}),
+		))
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/messages", testServer.URL), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer invalid")
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusTooManyRequests; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
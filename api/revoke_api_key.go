@@ -0,0 +1,29 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func (h *handler) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	apiKeyID := chi.URLParam(r, "api_key_id")
+
+	err := h.service.RevokeAPIKey(ctx, apiKeyID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			respondJSONStatus(w, &HTTPError{Message: "API key not found"}, http.StatusNotFound)
+			return
+		}
+		h.logger.Error("error revoking api key", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error revoking API key"}, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
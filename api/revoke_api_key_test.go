@@ -0,0 +1,71 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+func TestHandler_RevokeAPIKey(t *testing.T) {
+	t.Run("should forward API key id to service", func(t *testing.T) {
+		gotRevokeAPIKeyCalled := false
+		service := &mock.Service{
+			RevokeAPIKeyFunc: func(ctx context.Context, apiKeyID string) error {
+				gotRevokeAPIKeyCalled = true
+				if got, want := apiKeyID, "api-key-id"; got != want {
+					t.Errorf("got api key id %q, want %q", got, want)
+				}
+				return nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/api-keys/api-key-id", testServer.URL), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if !gotRevokeAPIKeyCalled {
+			t.Error("revoke api key should have been called")
+		}
+	})
+
+	t.Run("should return 404 if API key is not found", func(t *testing.T) {
+		service := &mock.Service{
+			RevokeAPIKeyFunc: func(ctx context.Context, apiKeyID string) error {
+				return fmt.Errorf("revoke api key: %w", model.ErrNotFound)
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/api-keys/api-key-id", testServer.URL), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := testServer.Client().Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
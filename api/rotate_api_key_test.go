@@ -0,0 +1,81 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestHandler_RotateAPIKey(t *testing.T) {
+	t.Run("should return rotated API key", func(t *testing.T) {
+		rotatedAt := time.Now()
+		wantAPIKey := model.IssuedAPIKey{
+			APIKey: model.APIKey{
+				ID:        "api-key-id",
+				UserName:  "user",
+				Scopes:    []string{model.ScopeMessagesRead},
+				CreatedAt: time.Now(),
+				RotatedAt: &rotatedAt,
+			},
+			Key: "msk_new-key",
+		}
+
+		service := &mock.Service{
+			RotateAPIKeyFunc: func(ctx context.Context, apiKeyID string) (model.IssuedAPIKey, error) {
+				if got, want := apiKeyID, "api-key-id"; got != want {
+					t.Errorf("got api key id %q, want %q", got, want)
+				}
+				return wantAPIKey, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Post(fmt.Sprintf("%s/api-keys/api-key-id/rotate", testServer.URL), "application/json", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		var gotAPIKey model.IssuedAPIKey
+		if err := json.NewDecoder(resp.Body).Decode(&gotAPIKey); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantAPIKey, gotAPIKey); diff != "" {
+			t.Errorf("api key mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return 404 if API key is not found", func(t *testing.T) {
+		service := &mock.Service{
+			RotateAPIKeyFunc: func(ctx context.Context, apiKeyID string) (model.IssuedAPIKey, error) {
+				return model.IssuedAPIKey{}, fmt.Errorf("rotate api key: %w", model.ErrNotFound)
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Post(fmt.Sprintf("%s/api-keys/api-key-id/rotate", testServer.URL), "application/json", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
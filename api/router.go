@@ -5,39 +5,120 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
 	"github.com/RichterMaximilian/osttra-coding-assignment/model"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
 type Service interface {
-	SubmitMessage(ctx context.Context, recipientUserName, messageContent string) (string, error)
-	FetchNewMessages(ctx context.Context) ([]model.Message, error)
+	SubmitMessage(ctx context.Context, submission model.MessageSubmission) (string, error)
+	SubmitMessages(ctx context.Context, submissions []model.MessageSubmission) ([]string, error)
+	FetchNewMessages(ctx context.Context, recipientUserName string) ([]model.Message, error)
+	WaitForNewMessages(ctx context.Context, recipientUserName string, wait time.Duration) ([]model.Message, error)
+	SubscribeNewMessages(ctx context.Context, recipientUserName string) <-chan model.MessageEvent
+	AcknowledgeMessages(ctx context.Context, messageIDs []string) error
+	MarkMessagesUnfetched(ctx context.Context, messageIDs []string) error
+	CancelScheduledMessage(ctx context.Context, messageID string) error
 	DeleteMessages(ctx context.Context, messageIDs []string) error
-	GetAllMessages(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error)
+	DeleteExistingMessages(ctx context.Context, messageIDs []string) (model.DeleteResult, error)
+	GetTrash(ctx context.Context, recipientUserName *string) ([]model.Message, error)
+	RestoreMessages(ctx context.Context, messageIDs []string) error
+	GetMessage(ctx context.Context, messageID string) (model.Message, error)
+	GetAllMessages(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error)
+	SearchMessages(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error)
+	GetSentMessages(ctx context.Context, senderUserName string) ([]model.Message, error)
+	GetReceivedMessages(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error)
+	GetDeadLetters(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error)
+	RedriveDeadLetters(ctx context.Context, messageIDs []string) error
+	CreateAPIKey(ctx context.Context, userName string, scopes []string) (model.IssuedAPIKey, error)
+	GetAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, apiKeyID string) error
+	RotateAPIKey(ctx context.Context, apiKeyID string) (model.IssuedAPIKey, error)
+	AuthenticateAPIKey(ctx context.Context, key string) (model.User, error)
+	GetUsage(ctx context.Context, recipientUserName string) (model.Usage, error)
 }
 
+// DefaultHeartbeatInterval is the duration after which an idle event stream
+// sends a comment to keep proxies from closing the connection.
+const DefaultHeartbeatInterval = 15 * time.Second
+
 type handler struct {
-	service Service
-	logger  *zap.Logger
+	service           Service
+	logger            *zap.Logger
+	heartbeatInterval time.Duration
+	authenticator     Authenticator
+	rateLimiter       RateLimiter
+	rateLimits        RateLimits
+	shutdown          <-chan struct{}
 }
 
-func NewRouter(service Service, logger *zap.Logger) *chi.Mux {
+type routerOptsFunc func(h *handler)
+
+func NewRouter(service Service, logger *zap.Logger, opts ...routerOptsFunc) *chi.Mux {
 	r := chi.NewRouter()
 	h := &handler{
-		service: service,
-		logger:  logger,
+		service:           service,
+		logger:            logger,
+		heartbeatInterval: DefaultHeartbeatInterval,
+	}
+
+	for _, opt := range opts {
+		opt(h)
 	}
 
-	r.Post("/messages", h.postMessage)
-	r.Get("/messages/new", h.getNewMessages)
-	r.Delete("/messages", h.deleteMessages)
-	r.Get("/messages", h.getAllMessages)
+	r.Use(h.rateLimitIP)
+	if h.authenticator != nil {
+		r.Use(h.authenticate)
+	}
+
+	send := r.With(h.rateLimit, h.requireScope(model.ScopeMessagesSend))
+	read := r.With(h.rateLimit, h.requireScope(model.ScopeMessagesRead))
+	del := r.With(h.rateLimit, h.requireScope(model.ScopeMessagesDelete))
+	admin := r.With(h.rateLimit, h.requireAdmin)
+
+	send.Post("/messages", h.postMessage)
+	send.Post("/messages/batch", h.postMessagesBatch)
+	read.Get("/users/{user_name}/messages/new", h.getNewMessages)
+	read.Get("/users/{user_name}/messages/sent", h.getSentMessages)
+	read.Get("/users/{user_name}/usage", h.getUsage)
+	read.Get("/messages/stream", h.streamMessages)
+	read.Get("/messages/events", h.getMessageEvents)
+	read.Post("/messages/ack", h.ackMessages)
+	read.Post("/messages/unfetch", h.unfetchMessages)
+	send.Post("/messages/{message_id}/cancel", h.cancelScheduledMessage)
+	del.Delete("/messages", h.deleteMessages)
+	read.Get("/messages/trash", h.getTrash)
+	del.Post("/messages/restore", h.restoreMessages)
+	read.Get("/messages", h.getAllMessages)
+	read.Get("/messages/search", h.searchMessages)
+	read.Get("/messages/{message_id}", h.getMessage)
+	admin.Get("/dead-letters", h.getDeadLetters)
+	admin.Post("/dead-letters/redrive", h.redriveDeadLetters)
+	admin.Post("/api-keys", h.postAPIKey)
+	admin.Get("/api-keys", h.getAPIKeys)
+	admin.Delete("/api-keys/{api_key_id}", h.revokeAPIKey)
+	admin.Post("/api-keys/{api_key_id}/rotate", h.rotateAPIKey)
 
 	return r
 }
 
+func RouterHeartbeatInterval(heartbeatInterval time.Duration) routerOptsFunc {
+	return func(h *handler) {
+		h.heartbeatInterval = heartbeatInterval
+	}
+}
+
+// RouterShutdown ends the open streams once the shutdown channel is closed, as
+// they would otherwise keep a graceful shutdown of the server waiting.
+func RouterShutdown(shutdown <-chan struct{}) routerOptsFunc {
+	return func(h *handler) {
+		h.shutdown = shutdown
+	}
+}
+
 type HTTPError struct {
 	Message string `json:"message"`
 }
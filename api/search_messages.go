@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"go.uber.org/zap"
+)
+
+type searchPageResponse struct {
+	Results    []model.SearchResult `json:"results"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+	PrevCursor string               `json:"prev_cursor,omitempty"`
+}
+
+func (h *handler) searchMessages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		respondJSONStatus(w, &HTTPError{Message: "q is required"}, http.StatusBadRequest)
+		return
+	}
+
+	limit, errMessage := parseLimit(r)
+	if errMessage != "" {
+		respondJSONStatus(w, &HTTPError{Message: errMessage}, http.StatusBadRequest)
+		return
+	}
+
+	var offset int
+	if cursorRaw := r.URL.Query().Get("cursor"); cursorRaw != "" {
+		var err error
+		offset, err = decodeSearchCursor(cursorRaw)
+		if err != nil {
+			respondJSONStatus(w, &HTTPError{Message: "cursor is invalid"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := h.service.SearchMessages(ctx, core.RestrictedUserName(ctx), query, limit, offset)
+	if err != nil {
+		h.logger.Error("error searching messages", zap.Error(err))
+		respondJSONStatus(w, &HTTPError{Message: "error searching messages"}, http.StatusInternalServerError)
+		return
+	}
+
+	results := page.Results
+	if results == nil {
+		results = []model.SearchResult{}
+	}
+
+	respondJSONStatus(w, &searchPageResponse{
+		Results:    results,
+		NextCursor: encodeSearchCursor(page.NextOffset),
+		PrevCursor: encodeSearchCursor(page.PrevOffset),
+	}, http.StatusOK)
+}
@@ -0,0 +1,156 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestHandler_SearchMessages(t *testing.T) {
+	t.Run("should return search results from service", func(t *testing.T) {
+		wantResults := []model.SearchResult{
+			{
+				Message: model.Message{
+					ID:                "id",
+					RecipientUserName: "recipient",
+					Content:           "hello world",
+					SentAt:            time.Date(2023, 4, 13, 19, 43, 23, 0, time.UTC),
+				},
+				Rank:    0.5,
+				Snippet: "<mark>hello</mark> world",
+			},
+		}
+
+		service := &mock.Service{
+			SearchMessagesFunc: func(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error) {
+				if got, want := query, "hello"; got != want {
+					t.Errorf("got query %q, want %q", got, want)
+				}
+				if got, want := limit, 10; got != want {
+					t.Errorf("got limit %d, want %d", got, want)
+				}
+				if got, want := offset, 0; got != want {
+					t.Errorf("got offset %d, want %d", got, want)
+				}
+				return model.SearchPage{Results: wantResults}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/search?q=hello&limit=10", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		var gotPage struct {
+			Results []model.SearchResult `json:"results"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&gotPage); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantResults, gotPage.Results); diff != "" {
+			t.Errorf("results mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return cursor that is accepted for the next page", func(t *testing.T) {
+		nextOffset := 10
+
+		var gotOffsets []int
+		service := &mock.Service{
+			SearchMessagesFunc: func(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error) {
+				gotOffsets = append(gotOffsets, offset)
+				return model.SearchPage{NextOffset: &nextOffset}, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/search?q=hello", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var gotPage struct {
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&gotPage); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		resp, err = testServer.Client().Get(fmt.Sprintf("%s/messages/search?q=hello&cursor=%s", testServer.URL, gotPage.NextCursor))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		if diff := cmp.Diff([]int{0, nextOffset}, gotOffsets); diff != "" {
+			t.Errorf("offsets mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return 400 if query is missing", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/search", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRespBody := "{\"message\":\"q is required\"}\n"
+		if got, want := string(respBody), wantRespBody; got != want {
+			t.Errorf("got response body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return 500 if service returns error", func(t *testing.T) {
+		service := &mock.Service{
+			SearchMessagesFunc: func(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error) {
+				return model.SearchPage{}, errors.New("error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/search?q=hello", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// streamPollInterval is the duration after which the stream looks for new
+// messages even without an event, e.g. for scheduled messages becoming
+// due or leases expiring.
+const streamPollInterval = 30 * time.Second
+
+const streamWriteTimeout = 10 * time.Second
+
+var upgrader = websocket.Upgrader{}
+
+// streamContext returns the context of a stream, which is canceled when the
+// server shuts down.
+func (h *handler) streamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-h.shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+func (h *handler) streamMessages(w http.ResponseWriter, r *http.Request) {
+	recipientUserName := r.URL.Query().Get("user_name")
+	if recipientUserName == "" {
+		respondJSONStatus(w, &HTTPError{Message: "user_name is required"}, http.StatusBadRequest)
+		return
+	}
+
+	if user, ok := core.UserFromContext(r.Context()); ok && user.Name != recipientUserName {
+		respondJSONStatus(w, &HTTPError{Message: "cannot stream messages of another user"}, http.StatusForbidden)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already replied with an error.
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := h.streamContext(r.Context())
+	defer cancel()
+
+	// Clients do not send messages, but reading is required to process
+	// control frames and to notice that the connection was closed.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	events := h.service.SubscribeNewMessages(ctx, recipientUserName)
+
+	ticker := time.NewTicker(streamPollInterval)
+	defer ticker.Stop()
+
+	for {
+		messages, err := h.service.FetchNewMessages(ctx, recipientUserName)
+		if err != nil {
+			if ctx.Err() == nil {
+				h.logger.Error("error fetching new messages", zap.Error(err))
+				conn.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "error fetching new messages"),
+					time.Now().Add(streamWriteTimeout),
+				)
+			}
+			return
+		}
+
+		for _, message := range messages {
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(&message); err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-events:
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+func TestHandler_StreamMessages(t *testing.T) {
+	t.Run("should push new messages after each event", func(t *testing.T) {
+		now := time.Date(2023, 4, 13, 19, 43, 23, 0, time.UTC)
+		batches := [][]model.Message{
+			{{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now}},
+			{{ID: "id2", RecipientUserName: "recipient", Content: "content2", SentAt: now}},
+		}
+
+		events := make(chan model.MessageEvent)
+		service := &mock.Service{
+			SubscribeNewMessagesFunc: func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+				if got, want := recipientUserName, "recipient"; got != want {
+					t.Errorf("got recipient user name %q, want %q", got, want)
+				}
+				return events
+			},
+			FetchNewMessagesFunc: func(ctx context.Context, recipientUserName string) ([]model.Message, error) {
+				if len(batches) == 0 {
+					return nil, nil
+				}
+				messages := batches[0]
+				batches = batches[1:]
+				return messages, nil
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+		defer testServer.Close()
+
+		url := fmt.Sprintf("ws%s/messages/stream?user_name=recipient", strings.TrimPrefix(testServer.URL, "http"))
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer conn.Close()
+
+		var gotMessage model.Message
+		if err := conn.ReadJSON(&gotMessage); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(model.Message{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now}, gotMessage); diff != "" {
+			t.Errorf("message mismatch (-want +got):\n%s", diff)
+		}
+
+		events <- model.MessageEvent{MessageID: "id2", RecipientUserName: "recipient"}
+
+		if err := conn.ReadJSON(&gotMessage); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(model.Message{ID: "id2", RecipientUserName: "recipient", Content: "content2", SentAt: now}, gotMessage); diff != "" {
+			t.Errorf("message mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should close connection if service returns error", func(t *testing.T) {
+		service := &mock.Service{
+			SubscribeNewMessagesFunc: func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+				return nil
+			},
+			FetchNewMessagesFunc: func(ctx context.Context, recipientUserName string) ([]model.Message, error) {
+				return nil, errors.New("error")
+			},
+		}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+		defer testServer.Close()
+
+		url := fmt.Sprintf("ws%s/messages/stream?user_name=recipient", strings.TrimPrefix(testServer.URL, "http"))
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer conn.Close()
+
+		_, _, err = conn.ReadMessage()
+		if !websocket.IsCloseError(err, websocket.CloseInternalServerErr) {
+			t.Errorf("got error %v, want close error %d", err, websocket.CloseInternalServerErr)
+		}
+	})
+
+	t.Run("should return 400 if user name is missing", func(t *testing.T) {
+		service := &mock.Service{}
+
+		testServer := httptest.NewServer(api.NewRouter(service, zap.NewNop()))
+		defer testServer.Close()
+
+		resp, err := testServer.Client().Get(fmt.Sprintf("%s/messages/stream", testServer.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got HTTP status %d, want %d", got, want)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrKeyNotFound is returned for key IDs that are not part of a key set.
+var ErrKeyNotFound = errors.New("key not found")
+
+// DefaultKeySetRefreshInterval is the duration after which a remote key set is
+// fetched again to pick up rotated keys.
+const DefaultKeySetRefreshInterval = time.Hour
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// parseKeySet reads the RSA signing keys of a JSON Web Key Set. Keys of other
+// types or for other uses are ignored.
+func parseKeySet(data []byte) (map[string]*rsa.PublicKey, error) {
+	var keySet jsonWebKeySet
+	if err := json.Unmarshal(data, &keySet); err != nil {
+		return nil, fmt.Errorf("decode key set: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(keySet.Keys))
+	for _, key := range keySet.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode modulus of key %q: %w", key.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode exponent of key %q: %w", key.Kid, err)
+		}
+
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+// lookupKey finds the key with the ID. Tokens without a key ID are accepted if
+// the key set holds a single key.
+func lookupKey(keys map[string]*rsa.PublicKey, kid string) (*rsa.PublicKey, error) {
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("key %q: %w", kid, ErrKeyNotFound)
+}
+
+// StaticKeySet is a key set that does not change while the service runs.
+type StaticKeySet struct {
+	keys map[string]*rsa.PublicKey
+}
+
+// LoadKeySetFile reads a JSON Web Key Set from a file.
+func LoadKeySetFile(path string) (*StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key set file: %w", err)
+	}
+
+	keys, err := parseKeySet(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StaticKeySet{keys: keys}, nil
+}
+
+func (s *StaticKeySet) Key(kid string) (*rsa.PublicKey, error) {
+	return lookupKey(s.keys, kid)
+}
+
+// RemoteKeySet is a key set served by an identity provider. It is fetched
+// again periodically, so that keys can be rotated without a restart.
+type RemoteKeySet struct {
+	url             string
+	logger          *zap.Logger
+	client          *http.Client
+	refreshInterval time.Duration
+
+	mu   sync.RWMutex
+	keys map[string]*rsa.PublicKey
+}
+
+type remoteKeySetOptsFunc func(s *RemoteKeySet)
+
+// NewRemoteKeySet creates a key set served at url. It holds no keys until it
+// is refreshed.
+func NewRemoteKeySet(url string, logger *zap.Logger, opts ...remoteKeySetOptsFunc) *RemoteKeySet {
+	s := &RemoteKeySet{
+		url:             url,
+		logger:          logger,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: DefaultKeySetRefreshInterval,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func RemoteKeySetHTTPClient(client *http.Client) remoteKeySetOptsFunc {
+	return func(s *RemoteKeySet) {
+		s.client = client
+	}
+}
+
+func RemoteKeySetRefreshInterval(refreshInterval time.Duration) remoteKeySetOptsFunc {
+	return func(s *RemoteKeySet) {
+		s.refreshInterval = refreshInterval
+	}
+}
+
+func (s *RemoteKeySet) Key(kid string) (*rsa.PublicKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return lookupKey(s.keys, kid)
+}
+
+// Refresh fetches the key set. If fetching fails, the previous keys are kept.
+func (s *RemoteKeySet) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch key set: unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read key set: %w", err)
+	}
+
+	keys, err := parseKeySet(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Run refreshes the key set once per refresh interval until the context is
+// canceled.
+func (s *RemoteKeySet) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("failed to refresh key set", zap.Error(err))
+			}
+		}
+	}
+}
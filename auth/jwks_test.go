@@ -0,0 +1,175 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/auth"
+	"go.uber.org/zap"
+)
+
+func TestLoadKeySetFile(t *testing.T) {
+	t.Run("should load the RSA signing keys of the key set", func(t *testing.T) {
+		key := generateKey(t)
+
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		keySet := fmt.Sprintf(`{"keys": [%s, {"kty": "EC", "kid": "ec-kid"}, %s]}`, jsonWebKey("kid", "sig", key), jsonWebKey("enc-kid", "enc", key))
+		if err := os.WriteFile(path, []byte(keySet), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		s, err := auth.LoadKeySetFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotKey, err := s.Key("kid")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !gotKey.Equal(&key.PublicKey) {
+			t.Errorf("got key %v, want %v", gotKey, key.PublicKey)
+		}
+
+		for _, kid := range []string{"ec-kid", "enc-kid", "unknown-kid"} {
+			if _, err := s.Key(kid); !errors.Is(err, auth.ErrKeyNotFound) {
+				t.Errorf("got error %v for key %q, want %v", err, kid, auth.ErrKeyNotFound)
+			}
+		}
+	})
+
+	t.Run("should return the only key for tokens without key ID", func(t *testing.T) {
+		key := generateKey(t)
+
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		if err := os.WriteFile(path, []byte(fmt.Sprintf(`{"keys": [%s]}`, jsonWebKey("kid", "", key))), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		s, err := auth.LoadKeySetFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotKey, err := s.Key("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !gotKey.Equal(&key.PublicKey) {
+			t.Errorf("got key %v, want %v", gotKey, key.PublicKey)
+		}
+	})
+
+	t.Run("should return error if the file is invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := auth.LoadKeySetFile(path); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestRemoteKeySet_Refresh(t *testing.T) {
+	t.Run("should fetch the keys", func(t *testing.T) {
+		oldKey, newKey := generateKey(t), generateKey(t)
+
+		keySet := fmt.Sprintf(`{"keys": [%s]}`, jsonWebKey("old-kid", "sig", oldKey))
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, keySet)
+		}))
+		defer server.Close()
+
+		s := auth.NewRemoteKeySet(server.URL, zap.NewNop())
+
+		if _, err := s.Key("old-kid"); !errors.Is(err, auth.ErrKeyNotFound) {
+			t.Errorf("got error %v before refresh, want %v", err, auth.ErrKeyNotFound)
+		}
+
+		if err := s.Refresh(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := s.Key("old-kid"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		keySet = fmt.Sprintf(`{"keys": [%s]}`, jsonWebKey("new-kid", "sig", newKey))
+		if err := s.Refresh(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotKey, err := s.Key("new-kid")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !gotKey.Equal(&newKey.PublicKey) {
+			t.Errorf("got key %v, want %v", gotKey, newKey.PublicKey)
+		}
+
+		if _, err := s.Key("old-kid"); !errors.Is(err, auth.ErrKeyNotFound) {
+			t.Errorf("got error %v for rotated key, want %v", err, auth.ErrKeyNotFound)
+		}
+	})
+
+	t.Run("should keep the keys if fetching fails", func(t *testing.T) {
+		key := generateKey(t)
+
+		status := http.StatusOK
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"keys": [%s]}`, jsonWebKey("kid", "sig", key))
+		}))
+		defer server.Close()
+
+		s := auth.NewRemoteKeySet(server.URL, zap.NewNop())
+
+		if err := s.Refresh(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		status = http.StatusInternalServerError
+		if err := s.Refresh(context.Background()); err == nil {
+			t.Error("expected error")
+		}
+
+		if _, err := s.Key("kid"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return key
+}
+
+func jsonWebKey(kid, use string, key *rsa.PrivateKey) string {
+	return fmt.Sprintf(
+		`{"kty": "RSA", "kid": %q, "use": %q, "n": %q, "e": %q}`,
+		kid,
+		use,
+		base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+	)
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, expired or not
+// signed with a trusted key.
+var ErrInvalidToken = errors.New("invalid token")
+
+// DefaultUserClaim is the claim holding the name of the authenticated user.
+const DefaultUserClaim = "sub"
+
+// DefaultRolesClaim is the claim holding the roles of the authenticated user.
+const DefaultRolesClaim = "roles"
+
+// DefaultAdminRole is the role that makes a user an admin.
+const DefaultAdminRole = "admin"
+
+// KeySet provides the public keys that RS256 tokens are signed with.
+type KeySet interface {
+	Key(kid string) (*rsa.PublicKey, error)
+}
+
+// JWTAuthenticator validates bearer tokens signed with HS256 using a shared
+// secret or with RS256 using the keys of a key set. Tokens must expire.
+//
+// Users authenticated by a token are granted all scopes. Users with the admin
+// role are admins.
+type JWTAuthenticator struct {
+	hmacSecret []byte
+	keySet     KeySet
+	issuer     string
+	audience   string
+	userClaim  string
+	rolesClaim string
+	adminRole  string
+}
+
+type jwtAuthenticatorOptsFunc func(a *JWTAuthenticator)
+
+func NewJWTAuthenticator(opts ...jwtAuthenticatorOptsFunc) *JWTAuthenticator {
+	a := &JWTAuthenticator{
+		userClaim:  DefaultUserClaim,
+		rolesClaim: DefaultRolesClaim,
+		adminRole:  DefaultAdminRole,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// JWTAuthenticatorHMACSecret accepts tokens signed with HS256 using the secret.
+// An empty secret does not accept any HS256 tokens.
+func JWTAuthenticatorHMACSecret(secret []byte) jwtAuthenticatorOptsFunc {
+	return func(a *JWTAuthenticator) {
+		a.hmacSecret = secret
+	}
+}
+
+// JWTAuthenticatorKeySet accepts tokens signed with RS256 using a key of the
+// key set. A nil key set does not accept any RS256 tokens.
+func JWTAuthenticatorKeySet(keySet KeySet) jwtAuthenticatorOptsFunc {
+	return func(a *JWTAuthenticator) {
+		a.keySet = keySet
+	}
+}
+
+// JWTAuthenticatorIssuer requires the iss claim to match the issuer unless it
+// is empty.
+func JWTAuthenticatorIssuer(issuer string) jwtAuthenticatorOptsFunc {
+	return func(a *JWTAuthenticator) {
+		a.issuer = issuer
+	}
+}
+
+// JWTAuthenticatorAudience requires the aud claim to contain the audience
+// unless it is empty.
+func JWTAuthenticatorAudience(audience string) jwtAuthenticatorOptsFunc {
+	return func(a *JWTAuthenticator) {
+		a.audience = audience
+	}
+}
+
+func JWTAuthenticatorUserClaim(userClaim string) jwtAuthenticatorOptsFunc {
+	return func(a *JWTAuthenticator) {
+		a.userClaim = userClaim
+	}
+}
+
+// JWTAuthenticatorRolesClaim sets the claim holding the roles of the user,
+// either as an array or as a space-separated string.
+func JWTAuthenticatorRolesClaim(rolesClaim string) jwtAuthenticatorOptsFunc {
+	return func(a *JWTAuthenticator) {
+		a.rolesClaim = rolesClaim
+	}
+}
+
+func JWTAuthenticatorAdminRole(adminRole string) jwtAuthenticatorOptsFunc {
+	return func(a *JWTAuthenticator) {
+		a.adminRole = adminRole
+	}
+}
+
+// Authenticate validates the token and returns the user it was issued for.
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, token string) (model.User, error) {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, a.key, jwt.WithValidMethods(a.validMethods())); err != nil {
+		return model.User{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	// The parser only checks the expiry if the token has one, but tokens
+	// without an expiry would be valid forever.
+	if _, ok := claims["exp"]; !ok {
+		return model.User{}, fmt.Errorf("%w: token has no expiry", ErrInvalidToken)
+	}
+
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return model.User{}, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return model.User{}, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	userName, _ := claims[a.userClaim].(string)
+	if userName == "" {
+		return model.User{}, fmt.Errorf("%w: claim %q is missing", ErrInvalidToken, a.userClaim)
+	}
+
+	return model.User{
+		Name:   userName,
+		Scopes: append([]string(nil), model.Scopes...),
+		Admin:  hasRole(claims[a.rolesClaim], a.adminRole),
+	}, nil
+}
+
+func hasRole(roles interface{}, role string) bool {
+	var names []string
+	switch roles := roles.(type) {
+	case string:
+		names = strings.Fields(roles)
+	case []interface{}:
+		for _, name := range roles {
+			if name, ok := name.(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		if name == role {
+			return true
+		}
+	}
+	return false
+}
+
+// validMethods restricts the signing methods to the configured ones, which
+// keeps tokens signed with an unexpected algorithm from being accepted.
+func (a *JWTAuthenticator) validMethods() []string {
+	var methods []string
+	if len(a.hmacSecret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if a.keySet != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	return methods
+}
+
+func (a *JWTAuthenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method {
+	case jwt.SigningMethodHS256:
+		return a.hmacSecret, nil
+	case jwt.SigningMethodRS256:
+		kid, _ := token.Header["kid"].(string)
+		return a.keySet.Key(kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
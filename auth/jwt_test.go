@@ -0,0 +1,242 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/auth"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+var secret = []byte("secret")
+
+type staticKeySet map[string]*rsa.PublicKey
+
+func (s staticKeySet) Key(kid string) (*rsa.PublicKey, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, auth.ErrKeyNotFound
+	}
+	return key, nil
+}
+
+func TestJWTAuthenticator_Authenticate(t *testing.T) {
+	privateKey := generateKey(t)
+
+	validClaims := jwt.MapClaims{
+		"sub": "user",
+		"iss": "issuer",
+		"aud": "audience",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	t.Run("should return the user of a token signed with HS256", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret))
+
+		gotUser, err := a.Authenticate(context.Background(), signHS256(t, validClaims, secret))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantUser := model.User{Name: "user", Scopes: model.Scopes}
+		if diff := cmp.Diff(wantUser, gotUser); diff != "" {
+			t.Errorf("user mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return the user of a token signed with RS256", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorKeySet(staticKeySet{"kid": &privateKey.PublicKey}))
+
+		gotUser, err := a.Authenticate(context.Background(), signRS256(t, validClaims, "kid", privateKey))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantUser := model.User{Name: "user", Scopes: model.Scopes}
+		if diff := cmp.Diff(wantUser, gotUser); diff != "" {
+			t.Errorf("user mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should read the user from the configured claim", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret), auth.JWTAuthenticatorUserClaim("preferred_username"))
+
+		claims := jwt.MapClaims{
+			"sub":                "user-id",
+			"preferred_username": "user",
+			"exp":                time.Now().Add(time.Hour).Unix(),
+		}
+
+		gotUser, err := a.Authenticate(context.Background(), signHS256(t, claims, secret))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantUser := model.User{Name: "user", Scopes: model.Scopes}
+		if diff := cmp.Diff(wantUser, gotUser); diff != "" {
+			t.Errorf("user mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should make users with the admin role admins", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret))
+
+		for _, roles := range []interface{}{[]string{"user", "admin"}, "user admin"} {
+			claims := jwt.MapClaims{
+				"sub":   "user",
+				"roles": roles,
+				"exp":   time.Now().Add(time.Hour).Unix(),
+			}
+
+			gotUser, err := a.Authenticate(context.Background(), signHS256(t, claims, secret))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !gotUser.Admin {
+				t.Errorf("got no admin for roles %v", roles)
+			}
+		}
+
+		gotUser, err := a.Authenticate(context.Background(), signHS256(t, validClaims, secret))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if gotUser.Admin {
+			t.Error("got admin for token without roles")
+		}
+	})
+
+	t.Run("should reject tokens signed with another secret", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret))
+
+		_, err := a.Authenticate(context.Background(), signHS256(t, validClaims, []byte("other secret")))
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got error %v, want %v", err, auth.ErrInvalidToken)
+		}
+	})
+
+	t.Run("should reject tokens signed with an algorithm that is not configured", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret))
+
+		_, err := a.Authenticate(context.Background(), signRS256(t, validClaims, "kid", privateKey))
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got error %v, want %v", err, auth.ErrInvalidToken)
+		}
+	})
+
+	t.Run("should reject tokens signed with an unknown key", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorKeySet(staticKeySet{"kid": &privateKey.PublicKey}))
+
+		_, err := a.Authenticate(context.Background(), signRS256(t, validClaims, "other-kid", privateKey))
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got error %v, want %v", err, auth.ErrInvalidToken)
+		}
+	})
+
+	t.Run("should reject unsigned tokens", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret))
+
+		_, err := a.Authenticate(context.Background(), signNone(t, validClaims))
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got error %v, want %v", err, auth.ErrInvalidToken)
+		}
+	})
+
+	t.Run("should reject expired tokens", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret))
+
+		_, err := a.Authenticate(context.Background(), signHS256(t, jwt.MapClaims{
+			"sub": "user",
+			"exp": time.Now().Add(-time.Minute).Unix(),
+		}, secret))
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got error %v, want %v", err, auth.ErrInvalidToken)
+		}
+	})
+
+	t.Run("should reject tokens without expiry", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret))
+
+		_, err := a.Authenticate(context.Background(), signHS256(t, jwt.MapClaims{"sub": "user"}, secret))
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got error %v, want %v", err, auth.ErrInvalidToken)
+		}
+	})
+
+	t.Run("should reject tokens of another issuer", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret), auth.JWTAuthenticatorIssuer("other-issuer"))
+
+		_, err := a.Authenticate(context.Background(), signHS256(t, validClaims, secret))
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got error %v, want %v", err, auth.ErrInvalidToken)
+		}
+	})
+
+	t.Run("should reject tokens for another audience", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret), auth.JWTAuthenticatorAudience("other-audience"))
+
+		_, err := a.Authenticate(context.Background(), signHS256(t, validClaims, secret))
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got error %v, want %v", err, auth.ErrInvalidToken)
+		}
+	})
+
+	t.Run("should reject tokens without user", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret))
+
+		_, err := a.Authenticate(context.Background(), signHS256(t, jwt.MapClaims{
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}, secret))
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got error %v, want %v", err, auth.ErrInvalidToken)
+		}
+	})
+
+	t.Run("should reject malformed tokens", func(t *testing.T) {
+		a := auth.NewJWTAuthenticator(auth.JWTAuthenticatorHMACSecret(secret))
+
+		_, err := a.Authenticate(context.Background(), "token")
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got error %v, want %v", err, auth.ErrInvalidToken)
+		}
+	})
+}
+
+func signHS256(t *testing.T, claims jwt.MapClaims, secret []byte) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return token
+}
+
+func signRS256(t *testing.T, claims jwt.MapClaims, kid string, key *rsa.PrivateKey) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return signed
+}
+
+func signNone(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return token
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+)
+
+// apiKeyPrefix makes API keys recognizable, e.g. for secret scanners.
+const apiKeyPrefix = "msk_"
+
+// CreateAPIKey issues an API key for the user with the given scopes.
+func (s *Service) CreateAPIKey(ctx context.Context, userName string, scopes []string) (model.IssuedAPIKey, error) {
+	key, keyHash, err := generateAPIKey()
+	if err != nil {
+		return model.IssuedAPIKey{}, err
+	}
+
+	apiKey := model.APIKey{
+		ID:        s.uuid(),
+		UserName:  userName,
+		Scopes:    scopes,
+		CreatedAt: s.now(),
+	}
+
+	if err := s.repo.InsertAPIKey(ctx, apiKey, keyHash); err != nil {
+		return model.IssuedAPIKey{}, fmt.Errorf("insert api key: %w", err)
+	}
+
+	return model.IssuedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+func (s *Service) GetAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	apiKeys, err := s.repo.GetAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get api keys: %w", err)
+	}
+
+	return apiKeys, nil
+}
+
+func (s *Service) RevokeAPIKey(ctx context.Context, apiKeyID string) error {
+	if err := s.repo.RevokeAPIKey(ctx, apiKeyID, s.now()); err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+
+	return nil
+}
+
+// RotateAPIKey issues a new key for an API key, keeping its user and scopes.
+// The previous key stops working right away.
+func (s *Service) RotateAPIKey(ctx context.Context, apiKeyID string) (model.IssuedAPIKey, error) {
+	key, keyHash, err := generateAPIKey()
+	if err != nil {
+		return model.IssuedAPIKey{}, err
+	}
+
+	apiKey, err := s.repo.RotateAPIKey(ctx, apiKeyID, keyHash, s.now())
+	if err != nil {
+		return model.IssuedAPIKey{}, fmt.Errorf("rotate api key: %w", err)
+	}
+
+	return model.IssuedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// AuthenticateAPIKey returns the user an API key has been issued for, limited
+// to the scopes of the key. Unknown and revoked keys yield model.ErrNotFound.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string) (model.User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return model.User{}, fmt.Errorf("api key: %w", model.ErrNotFound)
+	}
+
+	apiKey, err := s.repo.GetAPIKeyByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		return model.User{}, fmt.Errorf("get api key: %w", err)
+	}
+
+	return model.User{Name: apiKey.UserName, Scopes: apiKey.Scopes, APIKeyID: apiKey.ID}, nil
+}
+
+// generateAPIKey returns a random key and its hash. The key has enough entropy
+// for a fast hash to be safe, which allows looking keys up by their hash.
+func generateAPIKey() (string, []byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("generate api key: %w", err)
+	}
+
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, hashAPIKey(key), nil
+}
+
+func hashAPIKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
@@ -0,0 +1,153 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/RichterMaximilian/osttra-coding-assignment/testhelpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestService_CreateAPIKey(t *testing.T) {
+	t.Run("should store the hash of a new key", func(t *testing.T) {
+		fakeTime := testhelpers.NewFakeTime(time.Now())
+		fakeUUID := func() string { return "uuid" }
+
+		wantAPIKey := model.APIKey{
+			ID:        "uuid",
+			UserName:  "user",
+			Scopes:    []string{model.ScopeMessagesSend},
+			CreatedAt: fakeTime.Now(),
+		}
+
+		var gotKeyHash []byte
+		repo := &mock.Repository{
+			InsertAPIKeyFunc: func(ctx context.Context, apiKey model.APIKey, keyHash []byte) error {
+				if diff := cmp.Diff(wantAPIKey, apiKey); diff != "" {
+					t.Errorf("api key mismatch (-want +got):\n%s", diff)
+				}
+				gotKeyHash = keyHash
+				return nil
+			},
+			GetAPIKeyByHashFunc: func(ctx context.Context, keyHash []byte) (model.APIKey, error) {
+				if !cmp.Equal(gotKeyHash, keyHash) {
+					return model.APIKey{}, model.ErrNotFound
+				}
+				return wantAPIKey, nil
+			},
+		}
+
+		service := core.NewService(repo, core.ServiceNow(fakeTime.Now), core.ServiceUUID(fakeUUID))
+
+		gotAPIKey, err := service.CreateAPIKey(context.Background(), "user", []string{model.ScopeMessagesSend})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantAPIKey, gotAPIKey.APIKey); diff != "" {
+			t.Errorf("api key mismatch (-want +got):\n%s", diff)
+		}
+
+		if !strings.HasPrefix(gotAPIKey.Key, "msk_") {
+			t.Errorf("got key %q, want prefix %q", gotAPIKey.Key, "msk_")
+		}
+
+		if cmp.Equal([]byte(gotAPIKey.Key), gotKeyHash) {
+			t.Error("key should not be stored in plain text")
+		}
+
+		gotUser, err := service.AuthenticateAPIKey(context.Background(), gotAPIKey.Key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantUser := model.User{Name: "user", Scopes: []string{model.ScopeMessagesSend}, APIKeyID: "uuid"}
+		if diff := cmp.Diff(wantUser, gotUser); diff != "" {
+			t.Errorf("user mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestService_RotateAPIKey(t *testing.T) {
+	t.Run("should store the hash of a new key", func(t *testing.T) {
+		fakeTime := testhelpers.NewFakeTime(time.Now())
+
+		var gotKeyHash []byte
+		repo := &mock.Repository{
+			RotateAPIKeyFunc: func(ctx context.Context, apiKeyID string, keyHash []byte, rotatedAt time.Time) (model.APIKey, error) {
+				if got, want := apiKeyID, "api-key-id"; got != want {
+					t.Errorf("got api key id %q, want %q", got, want)
+				}
+				if got, want := rotatedAt, fakeTime.Now(); !got.Equal(want) {
+					t.Errorf("got rotated at %v, want %v", got, want)
+				}
+				gotKeyHash = keyHash
+				return model.APIKey{ID: apiKeyID, RotatedAt: &rotatedAt}, nil
+			},
+		}
+
+		service := core.NewService(repo, core.ServiceNow(fakeTime.Now))
+
+		gotAPIKey, err := service.RotateAPIKey(context.Background(), "api-key-id")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if gotAPIKey.Key == "" {
+			t.Error("rotated key should be returned")
+		}
+
+		if len(gotKeyHash) == 0 {
+			t.Error("hash of the rotated key should be stored")
+		}
+	})
+
+	t.Run("should return error from repository", func(t *testing.T) {
+		repo := &mock.Repository{
+			RotateAPIKeyFunc: func(ctx context.Context, apiKeyID string, keyHash []byte, rotatedAt time.Time) (model.APIKey, error) {
+				return model.APIKey{}, model.ErrNotFound
+			},
+		}
+
+		service := core.NewService(repo)
+
+		_, err := service.RotateAPIKey(context.Background(), "api-key-id")
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("got error %v, want %v", err, model.ErrNotFound)
+		}
+	})
+}
+
+func TestService_AuthenticateAPIKey(t *testing.T) {
+	t.Run("should not look up keys without prefix", func(t *testing.T) {
+		repo := &mock.Repository{}
+
+		service := core.NewService(repo)
+
+		_, err := service.AuthenticateAPIKey(context.Background(), "key")
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("got error %v, want %v", err, model.ErrNotFound)
+		}
+	})
+
+	t.Run("should return error if key is unknown", func(t *testing.T) {
+		repo := &mock.Repository{
+			GetAPIKeyByHashFunc: func(ctx context.Context, keyHash []byte) (model.APIKey, error) {
+				return model.APIKey{}, model.ErrNotFound
+			},
+		}
+
+		service := core.NewService(repo)
+
+		_, err := service.AuthenticateAPIKey(context.Background(), "msk_key")
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("got error %v, want %v", err, model.ErrNotFound)
+		}
+	})
+}
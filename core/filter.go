@@ -0,0 +1,21 @@
+package core
+
+import "time"
+
+// MessageFilter narrows down the messages returned by GetAllMessages. Fields
+// that are nil do not filter.
+type MessageFilter struct {
+	// StartCursor is the ID of the first message to return.
+	StartCursor *string
+	// EndCursor is the ID of the last message to return.
+	EndCursor         *string
+	RecipientUserName *string
+	// Fetched selects either the messages that have been fetched at least once
+	// or the ones that have never been fetched.
+	Fetched    *bool
+	SentAfter  *time.Time
+	SentBefore *time.Time
+	// ContentContains selects the messages whose content contains the given
+	// text, ignoring case.
+	ContentContains *string
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+)
+
+// GetUsage returns what the recipient has accumulated and the quota that
+// applies to the recipient.
+func (s *Service) GetUsage(ctx context.Context, recipientUserName string) (model.Usage, error) {
+	usages, err := s.repo.GetUsages(ctx, []string{recipientUserName}, s.quota)
+	if err != nil {
+		return model.Usage{}, fmt.Errorf("get usage: %w", err)
+	}
+
+	return usages[0], nil
+}
+
+// checkQuotas returns a *model.QuotaExceededError if the messages would exceed
+// the quota of any of their recipients. Submissions running at the same time
+// are not taken into account, so a quota can be exceeded by a few messages.
+func (s *Service) checkQuotas(ctx context.Context, messages []model.Message) error {
+	type submitted struct {
+		messages int
+		bytes    int64
+	}
+
+	recipients := make([]string, 0, len(messages))
+	byRecipient := make(map[string]submitted, len(messages))
+	for _, message := range messages {
+		sub, ok := byRecipient[message.RecipientUserName]
+		if !ok {
+			recipients = append(recipients, message.RecipientUserName)
+		}
+		sub.messages++
+		sub.bytes += int64(len(message.Content))
+		byRecipient[message.RecipientUserName] = sub
+	}
+
+	usages, err := s.repo.GetUsages(ctx, recipients, s.quota)
+	if err != nil {
+		return fmt.Errorf("get usages: %w", err)
+	}
+
+	for _, usage := range usages {
+		sub := byRecipient[usage.UserName]
+		if max := usage.Quota.MaxUnfetchedMessages; max > 0 && usage.UnfetchedMessages+sub.messages > max {
+			return &model.QuotaExceededError{UserName: usage.UserName, Quota: model.QuotaUnfetchedMessages}
+		}
+		if max := usage.Quota.MaxBytes; max > 0 && usage.Bytes+sub.bytes > max {
+			return &model.QuotaExceededError{UserName: usage.UserName, Quota: model.QuotaBytes}
+		}
+	}
+
+	return nil
+}
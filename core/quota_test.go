@@ -0,0 +1,216 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func getEmptyUsages(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error) {
+	usages := make([]model.Usage, 0, len(recipientUserNames))
+	for _, recipientUserName := range recipientUserNames {
+		usages = append(usages, model.Usage{UserName: recipientUserName, Quota: defaultQuota})
+	}
+	return usages, nil
+}
+
+func TestService_GetUsage(t *testing.T) {
+	t.Run("should get usage with the default quota", func(t *testing.T) {
+		wantQuota := model.Quota{MaxUnfetchedMessages: 10, MaxBytes: 1000}
+		wantUsage := model.Usage{UserName: "recipient", UnfetchedMessages: 1, Bytes: 7, Quota: wantQuota}
+
+		repo := &mock.Repository{
+			GetUsagesFunc: func(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error) {
+				if diff := cmp.Diff([]string{"recipient"}, recipientUserNames); diff != "" {
+					t.Errorf("recipient user names mismatch (-want +got):\n%s", diff)
+				}
+				if diff := cmp.Diff(wantQuota, defaultQuota); diff != "" {
+					t.Errorf("quota mismatch (-want +got):\n%s", diff)
+				}
+				return []model.Usage{wantUsage}, nil
+			},
+		}
+
+		service := core.NewService(repo, core.ServiceQuota(wantQuota))
+
+		gotUsage, err := service.GetUsage(context.Background(), "recipient")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantUsage, gotUsage); diff != "" {
+			t.Errorf("usage mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestService_SubmitMessage_Quota(t *testing.T) {
+	t.Run("should return the original message of a retry even if the quota is exceeded", func(t *testing.T) {
+		wantMessageID := "existing-id"
+
+		repo := &mock.Repository{
+			GetIdempotentMessageIDFunc: func(ctx context.Context, senderUserName, idempotencyKey string, idempotencyWindow time.Duration) (string, error) {
+				if got, want := senderUserName, "sender"; got != want {
+					t.Errorf("got sender user name %q, want %q", got, want)
+				}
+				if got, want := idempotencyKey, "idempotency-key"; got != want {
+					t.Errorf("got idempotency key %q, want %q", got, want)
+				}
+				return wantMessageID, nil
+			},
+			GetUsagesFunc: func(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error) {
+				return []model.Usage{{UserName: "recipient", UnfetchedMessages: 2, Quota: model.Quota{MaxUnfetchedMessages: 2}}}, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		gotMessageID, err := service.SubmitMessage(context.Background(), model.MessageSubmission{
+			SenderUserName:    "sender",
+			RecipientUserName: "recipient",
+			Content:           "content",
+			IdempotencyKey:    "idempotency-key",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotMessageID, wantMessageID; got != want {
+			t.Errorf("got message ID %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should not insert message if the recipient has too many unfetched messages", func(t *testing.T) {
+		repo := &mock.Repository{
+			GetUsagesFunc: func(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error) {
+				return []model.Usage{{UserName: "recipient", UnfetchedMessages: 2, Quota: model.Quota{MaxUnfetchedMessages: 2}}}, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		_, err := service.SubmitMessage(context.Background(), model.MessageSubmission{RecipientUserName: "recipient", Content: "content"})
+
+		var quotaErr *model.QuotaExceededError
+		if !errors.As(err, &quotaErr) {
+			t.Fatalf("got error %v, want %T", err, quotaErr)
+		}
+
+		wantQuotaErr := &model.QuotaExceededError{UserName: "recipient", Quota: model.QuotaUnfetchedMessages}
+		if diff := cmp.Diff(wantQuotaErr, quotaErr); diff != "" {
+			t.Errorf("error mismatch (-want +got):\n%s", diff)
+		}
+
+		if !errors.Is(err, model.ErrQuotaExceeded) {
+			t.Errorf("got error %v, want %v", err, model.ErrQuotaExceeded)
+		}
+	})
+
+	t.Run("should not insert message if the content exceeds the bytes of the recipient", func(t *testing.T) {
+		repo := &mock.Repository{
+			GetUsagesFunc: func(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error) {
+				return []model.Usage{{UserName: "recipient", Bytes: 5, Quota: model.Quota{MaxBytes: 10}}}, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		_, err := service.SubmitMessage(context.Background(), model.MessageSubmission{RecipientUserName: "recipient", Content: "content"})
+
+		var quotaErr *model.QuotaExceededError
+		if !errors.As(err, &quotaErr) {
+			t.Fatalf("got error %v, want %T", err, quotaErr)
+		}
+
+		if got, want := quotaErr.Quota, model.QuotaBytes; got != want {
+			t.Errorf("got quota %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should insert message if it fits the quota exactly", func(t *testing.T) {
+		gotInsertMessageCall := false
+		repo := &mock.Repository{
+			GetUsagesFunc: func(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error) {
+				return []model.Usage{{UserName: "recipient", UnfetchedMessages: 1, Bytes: 3, Quota: model.Quota{MaxUnfetchedMessages: 2, MaxBytes: 10}}}, nil
+			},
+			InsertMessageFunc: func(ctx context.Context, message model.Message, idempotencyWindow time.Duration) (string, error) {
+				gotInsertMessageCall = true
+				return message.ID, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		if _, err := service.SubmitMessage(context.Background(), model.MessageSubmission{RecipientUserName: "recipient", Content: "content"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !gotInsertMessageCall {
+			t.Error("insert message should have been called")
+		}
+	})
+}
+
+func TestService_SubmitMessages_Quota(t *testing.T) {
+	t.Run("should count all messages of a recipient in the batch", func(t *testing.T) {
+		repo := &mock.Repository{
+			GetUsagesFunc: func(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error) {
+				return []model.Usage{{UserName: "recipient", Quota: model.Quota{MaxUnfetchedMessages: 1}}}, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		_, err := service.SubmitMessages(context.Background(), []model.MessageSubmission{
+			{RecipientUserName: "recipient", Content: "content-1"},
+			{RecipientUserName: "recipient", Content: "content-2"},
+		})
+		if !errors.Is(err, model.ErrQuotaExceeded) {
+			t.Errorf("got error %v, want %v", err, model.ErrQuotaExceeded)
+		}
+	})
+
+	t.Run("should get the usages of all recipients in the batch at once", func(t *testing.T) {
+		getUsagesCalls := 0
+		repo := &mock.Repository{
+			GetUsagesFunc: func(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error) {
+				getUsagesCalls++
+				if diff := cmp.Diff([]string{"recipient-1", "recipient-2"}, recipientUserNames); diff != "" {
+					t.Errorf("recipient user names mismatch (-want +got):\n%s", diff)
+				}
+				return []model.Usage{
+					{UserName: "recipient-1", Quota: model.Quota{MaxBytes: 100}},
+					{UserName: "recipient-2", Bytes: 95, Quota: model.Quota{MaxBytes: 100}},
+				}, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		_, err := service.SubmitMessages(context.Background(), []model.MessageSubmission{
+			{RecipientUserName: "recipient-1", Content: "content-1"},
+			{RecipientUserName: "recipient-2", Content: "content-2"},
+			{RecipientUserName: "recipient-1", Content: "content-3"},
+		})
+
+		var quotaErr *model.QuotaExceededError
+		if !errors.As(err, &quotaErr) {
+			t.Fatalf("got error %v, want %T", err, quotaErr)
+		}
+
+		wantQuotaErr := &model.QuotaExceededError{UserName: "recipient-2", Quota: model.QuotaBytes}
+		if diff := cmp.Diff(wantQuotaErr, quotaErr); diff != "" {
+			t.Errorf("error mismatch (-want +got):\n%s", diff)
+		}
+
+		if got, want := getUsagesCalls, 1; got != want {
+			t.Errorf("got %d calls to get usages, want %d", got, want)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+)
+
+// TokenBucket holds the requests a client can still make. Each request takes a
+// token and tokens are refilled at the rate of the limit.
+type TokenBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewTokenBucket returns a bucket that allows all requests of the limit.
+func NewTokenBucket(limit model.RateLimit, now time.Time) TokenBucket {
+	return TokenBucket{Tokens: float64(limit.Limit), UpdatedAt: now}
+}
+
+// Take refills the bucket for the time passed since it was last updated and
+// takes a token if one is left.
+func (b TokenBucket) Take(limit model.RateLimit, now time.Time) (TokenBucket, model.RateLimitResult) {
+	perSecond := float64(limit.Limit) / limit.Period.Seconds()
+
+	tokens := b.Tokens
+	updatedAt := b.UpdatedAt
+	if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		tokens = math.Min(float64(limit.Limit), tokens+elapsed.Seconds()*perSecond)
+		updatedAt = now
+	}
+
+	var result model.RateLimitResult
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsDuration((1 - tokens) / perSecond)
+	}
+	result.Remaining = int(tokens)
+	result.Reset = secondsDuration((float64(limit.Limit) - tokens) / perSecond)
+
+	return TokenBucket{Tokens: tokens, UpdatedAt: updatedAt}, result
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// memoryRateLimiterPruneInterval is the duration between two removals of full
+// buckets, which keeps clients that stopped sending from piling up.
+const memoryRateLimiterPruneInterval = time.Minute
+
+// MemoryRateLimiter keeps the token buckets in memory. Its limits only hold for
+// a single instance of the service.
+type MemoryRateLimiter struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	buckets  map[string]memoryBucket
+	prunedAt time.Time
+}
+
+type memoryBucket struct {
+	TokenBucket
+	fullAt time.Time
+}
+
+type memoryRateLimiterOptsFunc func(l *MemoryRateLimiter)
+
+func NewMemoryRateLimiter(opts ...memoryRateLimiterOptsFunc) *MemoryRateLimiter {
+	l := &MemoryRateLimiter{
+		now:     time.Now,
+		buckets: make(map[string]memoryBucket),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+func MemoryRateLimiterNow(now func() time.Time) memoryRateLimiterOptsFunc {
+	return func(l *MemoryRateLimiter) {
+		l.now = now
+	}
+}
+
+// Take takes a token from the bucket of the key.
+func (l *MemoryRateLimiter) Take(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket.TokenBucket = NewTokenBucket(limit, now)
+	}
+
+	var result model.RateLimitResult
+	bucket.TokenBucket, result = bucket.Take(limit, now)
+	bucket.fullAt = now.Add(result.Reset)
+	l.buckets[key] = bucket
+
+	return result, nil
+}
+
+// prune removes the buckets that have been refilled completely, as they are
+// equal to new ones.
+func (l *MemoryRateLimiter) prune(now time.Time) {
+	if now.Sub(l.prunedAt) < memoryRateLimiterPruneInterval {
+		return
+	}
+	l.prunedAt = now
+
+	for key, bucket := range l.buckets {
+		if !bucket.fullAt.After(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTokenBucket_Take(t *testing.T) {
+	limit := model.RateLimit{Limit: 2, Period: 2 * time.Second}
+
+	t.Run("should allow requests until the bucket is empty", func(t *testing.T) {
+		now := time.Now()
+		bucket := core.NewTokenBucket(limit, now)
+
+		bucket, result := bucket.Take(limit, now)
+		if diff := cmp.Diff(model.RateLimitResult{Allowed: true, Remaining: 1, Reset: time.Second}, result); diff != "" {
+			t.Errorf("result mismatch (-want +got):\n%s", diff)
+		}
+
+		bucket, result = bucket.Take(limit, now)
+		if diff := cmp.Diff(model.RateLimitResult{Allowed: true, Remaining: 0, Reset: 2 * time.Second}, result); diff != "" {
+			t.Errorf("result mismatch (-want +got):\n%s", diff)
+		}
+
+		_, result = bucket.Take(limit, now)
+		if diff := cmp.Diff(model.RateLimitResult{Allowed: false, Remaining: 0, RetryAfter: time.Second, Reset: 2 * time.Second}, result); diff != "" {
+			t.Errorf("result mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should refill the bucket over time", func(t *testing.T) {
+		now := time.Now()
+		bucket := core.TokenBucket{Tokens: 0, UpdatedAt: now}
+
+		_, result := bucket.Take(limit, now.Add(500*time.Millisecond))
+		if got, want := result.Allowed, false; got != want {
+			t.Errorf("got allowed %t, want %t", got, want)
+		}
+		if got, want := result.RetryAfter, 500*time.Millisecond; got != want {
+			t.Errorf("got retry after %v, want %v", got, want)
+		}
+
+		_, result = bucket.Take(limit, now.Add(time.Second))
+		if got, want := result.Allowed, true; got != want {
+			t.Errorf("got allowed %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should not refill the bucket beyond the limit", func(t *testing.T) {
+		now := time.Now()
+		bucket := core.TokenBucket{Tokens: 0, UpdatedAt: now}
+
+		gotBucket, _ := bucket.Take(limit, now.Add(time.Hour))
+		if got, want := gotBucket.Tokens, 1.0; got != want {
+			t.Errorf("got %v tokens, want %v", got, want)
+		}
+	})
+}
+
+func TestMemoryRateLimiter_Take(t *testing.T) {
+	limit := model.RateLimit{Limit: 1, Period: time.Minute}
+
+	t.Run("should limit each key on its own", func(t *testing.T) {
+		now := time.Now()
+		limiter := core.NewMemoryRateLimiter(core.MemoryRateLimiterNow(func() time.Time { return now }))
+
+		result, err := limiter.Take(context.Background(), "key-1", limit)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := result.Allowed, true; got != want {
+			t.Errorf("got allowed %t, want %t", got, want)
+		}
+
+		result, err = limiter.Take(context.Background(), "key-1", limit)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := result.Allowed, false; got != want {
+			t.Errorf("got allowed %t, want %t", got, want)
+		}
+
+		result, err = limiter.Take(context.Background(), "key-2", limit)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := result.Allowed, true; got != want {
+			t.Errorf("got allowed %t, want %t", got, want)
+		}
+	})
+
+	t.Run("should allow requests again once tokens are refilled", func(t *testing.T) {
+		now := time.Now()
+		limiter := core.NewMemoryRateLimiter(core.MemoryRateLimiterNow(func() time.Time { return now }))
+
+		if _, err := limiter.Take(context.Background(), "key", limit); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		now = now.Add(time.Minute)
+
+		result, err := limiter.Take(context.Background(), "key", limit)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := result.Allowed, true; got != want {
+			t.Errorf("got allowed %t, want %t", got, want)
+		}
+	})
+}
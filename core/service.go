@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,16 +11,69 @@ import (
 )
 
 type Repository interface {
-	InsertMessage(ctx context.Context, message model.Message) error
-	GetNewMessages(ctx context.Context) ([]model.Message, error)
-	DeleteMessages(ctx context.Context, messageIDs []string) error
-	GetAllMessages(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error)
+	InsertMessage(ctx context.Context, message model.Message, idempotencyWindow time.Duration) (string, error)
+	GetIdempotentMessageID(ctx context.Context, senderUserName, idempotencyKey string, idempotencyWindow time.Duration) (string, error)
+	InsertMessages(ctx context.Context, messages []model.Message) error
+	GetNewMessages(ctx context.Context, recipientUserName string, visibilityTimeout time.Duration, maxReceiveCount int) ([]model.Message, error)
+	AcknowledgeMessages(ctx context.Context, recipientUserName *string, messageIDs []string) error
+	MarkMessagesUnfetched(ctx context.Context, recipientUserName *string, messageIDs []string) error
+	CancelScheduledMessage(ctx context.Context, senderUserName *string, messageID string) error
+	DeleteMessages(ctx context.Context, recipientUserName *string, messageIDs []string) error
+	DeleteExistingMessages(ctx context.Context, recipientUserName *string, messageIDs []string) (model.DeleteResult, error)
+	GetTrash(ctx context.Context, recipientUserName *string) ([]model.Message, error)
+	RestoreMessages(ctx context.Context, recipientUserName *string, messageIDs []string) error
+	GetMessage(ctx context.Context, messageID string) (model.Message, error)
+	GetAllMessages(ctx context.Context, filter MessageFilter, page model.PageRequest) (model.MessagePage, error)
+	SearchMessages(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error)
+	GetSentMessages(ctx context.Context, senderUserName string) ([]model.Message, error)
+	GetReceivedMessages(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error)
+	GetDeadLetters(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error)
+	RedriveDeadLetters(ctx context.Context, messageIDs []string) error
+	InsertAPIKey(ctx context.Context, apiKey model.APIKey, keyHash []byte) error
+	GetAPIKeyByHash(ctx context.Context, keyHash []byte) (model.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, apiKeyID string, revokedAt time.Time) error
+	RotateAPIKey(ctx context.Context, apiKeyID string, keyHash []byte, rotatedAt time.Time) (model.APIKey, error)
+	GetUsages(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error)
 }
 
+// ChangeFeed delivers events about messages inserted by any service instance
+// sharing the database.
+type ChangeFeed interface {
+	Subscribe(ctx context.Context, recipientUserName string) <-chan model.MessageEvent
+}
+
+type noopChangeFeed struct{}
+
+func (noopChangeFeed) Subscribe(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+	return nil
+}
+
+// DefaultVisibilityTimeout is the duration for which fetched messages are
+// hidden from further fetches unless they are acknowledged.
+const DefaultVisibilityTimeout = 30 * time.Second
+
+// DefaultMaxReceiveCount is the number of times a message is fetched without
+// being acknowledged before it is moved to the dead letters.
+const DefaultMaxReceiveCount = 5
+
+// DefaultIdempotencyWindow is the duration during which a message submitted
+// again with the same idempotency key is not inserted again.
+const DefaultIdempotencyWindow = 24 * time.Hour
+
+// DefaultPageLimit is the number of messages in a page if the client does not
+// ask for a different number.
+const DefaultPageLimit = 100
+
 type Service struct {
-	repo Repository
-	now  nowFunc
-	uuid uuidFunc
+	repo              Repository
+	now               nowFunc
+	uuid              uuidFunc
+	visibilityTimeout time.Duration
+	maxReceiveCount   int
+	idempotencyWindow time.Duration
+	changeFeed        ChangeFeed
+	quota             model.Quota
 }
 
 type nowFunc func() time.Time
@@ -30,9 +84,13 @@ type serviceOptsFunc func(s *Service)
 
 func NewService(repo Repository, opts ...serviceOptsFunc) *Service {
 	s := &Service{
-		repo: repo,
-		now:  time.Now,
-		uuid: uuid.NewString,
+		repo:              repo,
+		now:               time.Now,
+		uuid:              uuid.NewString,
+		visibilityTimeout: DefaultVisibilityTimeout,
+		maxReceiveCount:   DefaultMaxReceiveCount,
+		idempotencyWindow: DefaultIdempotencyWindow,
+		changeFeed:        noopChangeFeed{},
 	}
 
 	for _, opt := range opts {
@@ -54,23 +112,125 @@ func ServiceUUID(uuid uuidFunc) serviceOptsFunc {
 	}
 }
 
-func (s *Service) SubmitMessage(ctx context.Context, recipientUserName, messageContent string) (string, error) {
+func ServiceVisibilityTimeout(visibilityTimeout time.Duration) serviceOptsFunc {
+	return func(s *Service) {
+		s.visibilityTimeout = visibilityTimeout
+	}
+}
+
+// ServiceMaxReceiveCount sets the number of deliveries after which unacknowledged
+// messages are moved to the dead letters. A value of zero disables dead-lettering.
+func ServiceMaxReceiveCount(maxReceiveCount int) serviceOptsFunc {
+	return func(s *Service) {
+		s.maxReceiveCount = maxReceiveCount
+	}
+}
+
+func ServiceIdempotencyWindow(idempotencyWindow time.Duration) serviceOptsFunc {
+	return func(s *Service) {
+		s.idempotencyWindow = idempotencyWindow
+	}
+}
+
+func ServiceChangeFeed(changeFeed ChangeFeed) serviceOptsFunc {
+	return func(s *Service) {
+		s.changeFeed = changeFeed
+	}
+}
+
+// ServiceQuota sets the quota of recipients without a quota of their own. The
+// zero value does not cap anything.
+func ServiceQuota(quota model.Quota) serviceOptsFunc {
+	return func(s *Service) {
+		s.quota = quota
+	}
+}
+
+// SubmitMessage stores a new message and returns its ID. If the sender already
+// submitted a message with the same idempotency key, the ID of that message is
+// returned instead. If the message would exceed the quota of the recipient, a
+// *model.QuotaExceededError is returned.
+func (s *Service) SubmitMessage(ctx context.Context, submission model.MessageSubmission) (string, error) {
+	sentAt := s.now()
 	message := model.Message{
 		ID:                s.uuid(),
-		RecipientUserName: recipientUserName,
-		Content:           messageContent,
-		SentAt:            s.now(),
+		SenderUserName:    submission.SenderUserName,
+		RecipientUserName: submission.RecipientUserName,
+		Content:           submission.Content,
+		SentAt:            sentAt,
+		DeliverAt:         submission.DeliverAt,
+		ExpiresAt:         expiresAt(sentAt, submission.TTL),
+		IdempotencyKey:    submission.IdempotencyKey,
+	}
+
+	if message.IdempotencyKey != "" {
+		// A retry must return the original message even if the mailbox of the
+		// recipient has been filled up since.
+		messageID, err := s.repo.GetIdempotentMessageID(ctx, message.SenderUserName, message.IdempotencyKey, s.idempotencyWindow)
+		if err == nil {
+			return messageID, nil
+		}
+		if !errors.Is(err, model.ErrNotFound) {
+			return "", fmt.Errorf("get idempotent message id: %w", err)
+		}
 	}
 
-	if err := s.repo.InsertMessage(ctx, message); err != nil {
+	if err := s.checkQuotas(ctx, []model.Message{message}); err != nil {
+		return "", err
+	}
+
+	messageID, err := s.repo.InsertMessage(ctx, message, s.idempotencyWindow)
+	if err != nil {
 		return "", fmt.Errorf("insert message: %w", err)
 	}
 
-	return message.ID, nil
+	return messageID, nil
+}
+
+// SubmitMessages stores all messages at once and returns their IDs in the
+// order of the submissions. Idempotency keys are not supported for batches. If
+// the messages would exceed the quota of any recipient, none is stored and a
+// *model.QuotaExceededError is returned.
+func (s *Service) SubmitMessages(ctx context.Context, submissions []model.MessageSubmission) ([]string, error) {
+	sentAt := s.now()
+	messages := make([]model.Message, 0, len(submissions))
+	messageIDs := make([]string, 0, len(submissions))
+	for _, submission := range submissions {
+		message := model.Message{
+			ID:                s.uuid(),
+			SenderUserName:    submission.SenderUserName,
+			RecipientUserName: submission.RecipientUserName,
+			Content:           submission.Content,
+			SentAt:            sentAt,
+			DeliverAt:         submission.DeliverAt,
+			ExpiresAt:         expiresAt(sentAt, submission.TTL),
+		}
+		messages = append(messages, message)
+		messageIDs = append(messageIDs, message.ID)
+	}
+
+	if err := s.checkQuotas(ctx, messages); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.InsertMessages(ctx, messages); err != nil {
+		return nil, fmt.Errorf("insert messages: %w", err)
+	}
+
+	return messageIDs, nil
+}
+
+func expiresAt(sentAt time.Time, ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+
+	expiresAt := sentAt.Add(ttl)
+	return &expiresAt
 }
 
-func (s *Service) FetchNewMessages(ctx context.Context) ([]model.Message, error) {
-	messages, err := s.repo.GetNewMessages(ctx)
+func (s *Service) FetchNewMessages(ctx context.Context, recipientUserName string) ([]model.Message, error) {
+	messages, err := s.repo.GetNewMessages(ctx, recipientUserName, s.visibilityTimeout, s.maxReceiveCount)
 	if err != nil {
 		return nil, fmt.Errorf("get new messages: %w", err)
 	}
@@ -78,19 +238,205 @@ func (s *Service) FetchNewMessages(ctx context.Context) ([]model.Message, error)
 	return messages, nil
 }
 
+// WaitForNewMessages fetches new messages of the recipient like
+// FetchNewMessages. If there are none, it waits up to the given duration for
+// new messages to be submitted instead of returning immediately.
+func (s *Service) WaitForNewMessages(ctx context.Context, recipientUserName string, wait time.Duration) ([]model.Message, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribing before fetching ensures that messages submitted in between
+	// are not missed.
+	events := s.changeFeed.Subscribe(ctx, recipientUserName)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		messages, err := s.FetchNewMessages(ctx, recipientUserName)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(messages) > 0 {
+			return messages, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			// Scheduled messages becoming due do not cause events, so they
+			// are looked for one last time.
+			return s.FetchNewMessages(ctx, recipientUserName)
+		case <-events:
+		}
+	}
+}
+
+// SubscribeNewMessages returns a channel that receives an event whenever a
+// message for the recipient is inserted. The subscription ends when the
+// context is canceled.
+func (s *Service) SubscribeNewMessages(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+	return s.changeFeed.Subscribe(ctx, recipientUserName)
+}
+
+func (s *Service) AcknowledgeMessages(ctx context.Context, messageIDs []string) error {
+	if err := s.repo.AcknowledgeMessages(ctx, RestrictedUserName(ctx), messageIDs); err != nil {
+		return fmt.Errorf("acknowledge messages: %w", err)
+	}
+
+	return nil
+}
+
+// MarkMessagesUnfetched re-queues messages, so that they are returned as new
+// messages again without being submitted again.
+func (s *Service) MarkMessagesUnfetched(ctx context.Context, messageIDs []string) error {
+	if err := s.repo.MarkMessagesUnfetched(ctx, RestrictedUserName(ctx), messageIDs); err != nil {
+		return fmt.Errorf("mark messages unfetched: %w", err)
+	}
+
+	return nil
+}
+
+// CancelScheduledMessage deletes a message that has not been delivered yet
+// because its delivery time lies in the future. Only admins can cancel messages
+// of other senders, otherwise model.ErrForbidden is returned.
+func (s *Service) CancelScheduledMessage(ctx context.Context, messageID string) error {
+	if err := s.repo.CancelScheduledMessage(ctx, RestrictedUserName(ctx), messageID); err != nil {
+		return fmt.Errorf("cancel scheduled message: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteMessages moves all given messages to the trash or, if any of them does
+// not exist, none of them. Only admins can delete messages of other recipients,
+// otherwise model.ErrForbidden is returned.
 func (s *Service) DeleteMessages(ctx context.Context, messageIDs []string) error {
-	if err := s.repo.DeleteMessages(ctx, messageIDs); err != nil {
+	if err := s.repo.DeleteMessages(ctx, RestrictedUserName(ctx), messageIDs); err != nil {
 		return fmt.Errorf("delete messages: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Service) GetAllMessages(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error) {
-	messages, err := s.repo.GetAllMessages(ctx, startCursor, endCursor)
+// DeleteExistingMessages moves those of the given messages that exist to the
+// trash. Only admins can delete messages of other recipients, otherwise
+// model.ErrForbidden is returned.
+func (s *Service) DeleteExistingMessages(ctx context.Context, messageIDs []string) (model.DeleteResult, error) {
+	result, err := s.repo.DeleteExistingMessages(ctx, RestrictedUserName(ctx), messageIDs)
 	if err != nil {
-		return nil, fmt.Errorf("get all messages: %w", err)
+		return model.DeleteResult{}, fmt.Errorf("delete existing messages: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetTrash returns the deleted messages that have not been purged yet.
+func (s *Service) GetTrash(ctx context.Context, recipientUserName *string) ([]model.Message, error) {
+	messages, err := s.repo.GetTrash(ctx, recipientUserName)
+	if err != nil {
+		return nil, fmt.Errorf("get trash: %w", err)
 	}
 
 	return messages, nil
 }
+
+// RestoreMessages moves deleted messages back from the trash. Only admins can
+// restore messages of other recipients, otherwise model.ErrForbidden is
+// returned.
+func (s *Service) RestoreMessages(ctx context.Context, messageIDs []string) error {
+	if err := s.repo.RestoreMessages(ctx, RestrictedUserName(ctx), messageIDs); err != nil {
+		return fmt.Errorf("restore messages: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) GetMessage(ctx context.Context, messageID string) (model.Message, error) {
+	message, err := s.repo.GetMessage(ctx, messageID)
+	if err != nil {
+		return model.Message{}, fmt.Errorf("get message: %w", err)
+	}
+
+	// Scheduled messages have not been delivered yet, so only their sender
+	// may see them.
+	if message.DeliverAt != nil && message.DeliverAt.After(s.now()) {
+		if user, ok := UserFromContext(ctx); !ok || user.Name != message.SenderUserName {
+			return model.Message{}, fmt.Errorf("get message: %w", model.ErrNotFound)
+		}
+	}
+
+	return message, nil
+}
+
+func (s *Service) GetAllMessages(ctx context.Context, filter MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+	if page.Limit <= 0 {
+		page.Limit = DefaultPageLimit
+	}
+
+	messagePage, err := s.repo.GetAllMessages(ctx, filter, page)
+	if err != nil {
+		return model.MessagePage{}, fmt.Errorf("get all messages: %w", err)
+	}
+
+	return messagePage, nil
+}
+
+// SearchMessages finds the messages whose content matches the full-text query,
+// the most relevant first. If recipientUserName is set, only messages of that
+// recipient are searched.
+func (s *Service) SearchMessages(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error) {
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+
+	page, err := s.repo.SearchMessages(ctx, recipientUserName, query, limit, offset)
+	if err != nil {
+		return model.SearchPage{}, fmt.Errorf("search messages: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *Service) GetSentMessages(ctx context.Context, senderUserName string) ([]model.Message, error) {
+	messages, err := s.repo.GetSentMessages(ctx, senderUserName)
+	if err != nil {
+		return nil, fmt.Errorf("get sent messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// GetReceivedMessages returns up to limit delivered messages of the recipient
+// following the message afterCursor without leasing them.
+func (s *Service) GetReceivedMessages(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+
+	messages, err := s.repo.GetReceivedMessages(ctx, recipientUserName, afterCursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get received messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (s *Service) GetDeadLetters(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+	deadLetters, err := s.repo.GetDeadLetters(ctx, recipientUserName)
+	if err != nil {
+		return nil, fmt.Errorf("get dead letters: %w", err)
+	}
+
+	return deadLetters, nil
+}
+
+func (s *Service) RedriveDeadLetters(ctx context.Context, messageIDs []string) error {
+	if err := s.repo.RedriveDeadLetters(ctx, messageIDs); err != nil {
+		return fmt.Errorf("redrive dead letters: %w", err)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package core_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -12,34 +13,53 @@ import (
 	"github.com/google/go-cmp/cmp"
 )
 
+func getNoIdempotentMessageID(ctx context.Context, senderUserName, idempotencyKey string, idempotencyWindow time.Duration) (string, error) {
+	return "", model.ErrNotFound
+}
+
 func TestService_SubmitMessage(t *testing.T) {
 	t.Run("should submit message", func(t *testing.T) {
 		fakeTime := testhelpers.NewFakeTime(time.Now())
 		wantUUID := "uuid"
 		fakeUUID := func() string { return wantUUID }
+		deliverAt := fakeTime.Now().Add(time.Hour)
 
 		wantMessage := model.Message{
 			ID:                wantUUID,
+			SenderUserName:    "sender",
 			RecipientUserName: "recipient",
 			Content:           "content",
 			SentAt:            fakeTime.Now(),
+			DeliverAt:         &deliverAt,
 			FetchedAt:         nil,
+			IdempotencyKey:    "idempotency-key",
 		}
 
 		gotInsertMessageCall := false
 		repo := &mock.Repository{
-			InsertMessageFunc: func(ctx context.Context, message model.Message) error {
+			GetIdempotentMessageIDFunc: getNoIdempotentMessageID,
+			GetUsagesFunc:              getEmptyUsages,
+			InsertMessageFunc: func(ctx context.Context, message model.Message, idempotencyWindow time.Duration) (string, error) {
 				gotInsertMessageCall = true
 				if diff := cmp.Diff(wantMessage, message); diff != "" {
 					t.Errorf("message mismatch (-want +got):\n%s", diff)
 				}
-				return nil
+				if got, want := idempotencyWindow, time.Hour; got != want {
+					t.Errorf("got idempotency window %v, want %v", got, want)
+				}
+				return message.ID, nil
 			},
 		}
 
-		service := core.NewService(repo, core.ServiceNow(fakeTime.Now), core.ServiceUUID(fakeUUID))
+		service := core.NewService(repo, core.ServiceNow(fakeTime.Now), core.ServiceUUID(fakeUUID), core.ServiceIdempotencyWindow(time.Hour))
 
-		gotMessageID, err := service.SubmitMessage(context.Background(), wantMessage.RecipientUserName, wantMessage.Content)
+		gotMessageID, err := service.SubmitMessage(context.Background(), model.MessageSubmission{
+			SenderUserName:    wantMessage.SenderUserName,
+			RecipientUserName: wantMessage.RecipientUserName,
+			Content:           wantMessage.Content,
+			IdempotencyKey:    wantMessage.IdempotencyKey,
+			DeliverAt:         wantMessage.DeliverAt,
+		})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -53,6 +73,129 @@ func TestService_SubmitMessage(t *testing.T) {
 			t.Errorf("got insert message call %v, want %v", got, want)
 		}
 	})
+
+	t.Run("should set expiry of message from ttl", func(t *testing.T) {
+		fakeTime := testhelpers.NewFakeTime(time.Now())
+		wantExpiresAt := fakeTime.Now().Add(time.Minute)
+
+		repo := &mock.Repository{
+			GetUsagesFunc: getEmptyUsages,
+			InsertMessageFunc: func(ctx context.Context, message model.Message, idempotencyWindow time.Duration) (string, error) {
+				if diff := cmp.Diff(&wantExpiresAt, message.ExpiresAt); diff != "" {
+					t.Errorf("expires at mismatch (-want +got):\n%s", diff)
+				}
+				return message.ID, nil
+			},
+		}
+
+		service := core.NewService(repo, core.ServiceNow(fakeTime.Now))
+
+		_, err := service.SubmitMessage(context.Background(), model.MessageSubmission{
+			SenderUserName:    "sender",
+			RecipientUserName: "recipient",
+			Content:           "content",
+			TTL:               time.Minute,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should return ID of message submitted before with same idempotency key", func(t *testing.T) {
+		wantMessageID := "existing-id"
+
+		repo := &mock.Repository{
+			GetIdempotentMessageIDFunc: getNoIdempotentMessageID,
+			GetUsagesFunc:              getEmptyUsages,
+			InsertMessageFunc: func(ctx context.Context, message model.Message, idempotencyWindow time.Duration) (string, error) {
+				return wantMessageID, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		gotMessageID, err := service.SubmitMessage(context.Background(), model.MessageSubmission{
+			SenderUserName:    "sender",
+			RecipientUserName: "recipient",
+			Content:           "content",
+			IdempotencyKey:    "idempotency-key",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotMessageID, wantMessageID; got != want {
+			t.Errorf("got message ID %q, want %q", got, want)
+		}
+	})
+}
+
+func TestService_SubmitMessages(t *testing.T) {
+	t.Run("should submit messages", func(t *testing.T) {
+		fakeTime := testhelpers.NewFakeTime(time.Now())
+		uuids := []string{"uuid-1", "uuid-2"}
+		fakeUUID := func() string {
+			uuid := uuids[0]
+			uuids = uuids[1:]
+			return uuid
+		}
+
+		wantMessages := []model.Message{
+			{
+				ID:                "uuid-1",
+				SenderUserName:    "sender",
+				RecipientUserName: "recipient-1",
+				Content:           "content-1",
+				SentAt:            fakeTime.Now(),
+			},
+			{
+				ID:                "uuid-2",
+				SenderUserName:    "sender",
+				RecipientUserName: "recipient-2",
+				Content:           "content-2",
+				SentAt:            fakeTime.Now(),
+			},
+		}
+
+		gotInsertMessagesCall := false
+		repo := &mock.Repository{
+			GetUsagesFunc: getEmptyUsages,
+			InsertMessagesFunc: func(ctx context.Context, messages []model.Message) error {
+				gotInsertMessagesCall = true
+				if diff := cmp.Diff(wantMessages, messages); diff != "" {
+					t.Errorf("messages mismatch (-want +got):\n%s", diff)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo, core.ServiceNow(fakeTime.Now), core.ServiceUUID(fakeUUID))
+
+		gotMessageIDs, err := service.SubmitMessages(context.Background(), []model.MessageSubmission{
+			{
+				SenderUserName:    "sender",
+				RecipientUserName: "recipient-1",
+				Content:           "content-1",
+			},
+			{
+				SenderUserName:    "sender",
+				RecipientUserName: "recipient-2",
+				Content:           "content-2",
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff([]string{"uuid-1", "uuid-2"}, gotMessageIDs); diff != "" {
+			t.Errorf("message IDs mismatch (-want +got):\n%s", diff)
+		}
+
+		wantInsertMessagesCall := true
+		if got, want := gotInsertMessagesCall, wantInsertMessagesCall; got != want {
+			t.Errorf("got insert messages call %v, want %v", got, want)
+		}
+	})
 }
 
 func TestService_FetchNewMessages(t *testing.T) {
@@ -68,14 +211,68 @@ func TestService_FetchNewMessages(t *testing.T) {
 		}
 
 		repo := &mock.Repository{
-			GetNewMessagesFunc: func(ctx context.Context) ([]model.Message, error) {
+			GetNewMessagesFunc: func(ctx context.Context, recipientUserName string, visibilityTimeout time.Duration, maxReceiveCount int) ([]model.Message, error) {
+				if got, want := recipientUserName, "recipient"; got != want {
+					t.Errorf("got recipient user name %q, want %q", got, want)
+				}
+				if got, want := visibilityTimeout, time.Minute; got != want {
+					t.Errorf("got visibility timeout %v, want %v", got, want)
+				}
+				if got, want := maxReceiveCount, 3; got != want {
+					t.Errorf("got max receive count %d, want %d", got, want)
+				}
 				return wantMessages, nil
 			},
 		}
 
-		service := core.NewService(repo)
+		service := core.NewService(repo, core.ServiceVisibilityTimeout(time.Minute), core.ServiceMaxReceiveCount(3))
+
+		gotMessages, err := service.FetchNewMessages(context.Background(), "recipient")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Errorf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestService_WaitForNewMessages(t *testing.T) {
+	t.Run("should fetch new messages after event", func(t *testing.T) {
+		wantMessages := []model.Message{
+			{
+				ID:                "id",
+				RecipientUserName: "recipient",
+				Content:           "content",
+				SentAt:            time.Now(),
+			},
+		}
+
+		events := make(chan model.MessageEvent, 1)
+		gotGetNewMessagesCalls := 0
+		repo := &mock.Repository{
+			GetNewMessagesFunc: func(ctx context.Context, recipientUserName string, visibilityTimeout time.Duration, maxReceiveCount int) ([]model.Message, error) {
+				gotGetNewMessagesCalls++
+				if gotGetNewMessagesCalls == 1 {
+					events <- model.MessageEvent{MessageID: "id2", RecipientUserName: "recipient"}
+					return nil, nil
+				}
+				return wantMessages, nil
+			},
+		}
+		changeFeed := &mock.ChangeFeed{
+			SubscribeFunc: func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+				if got, want := recipientUserName, "recipient"; got != want {
+					t.Errorf("got recipient user name %q, want %q", got, want)
+				}
+				return events
+			},
+		}
+
+		service := core.NewService(repo, core.ServiceChangeFeed(changeFeed))
 
-		gotMessages, err := service.FetchNewMessages(context.Background())
+		gotMessages, err := service.WaitForNewMessages(context.Background(), "recipient", time.Hour)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -83,6 +280,169 @@ func TestService_FetchNewMessages(t *testing.T) {
 		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
 			t.Errorf("messages mismatch (-want +got):\n%s", diff)
 		}
+
+		if got, want := gotGetNewMessagesCalls, 2; got != want {
+			t.Errorf("got %d get new messages calls, want %d", got, want)
+		}
+	})
+
+	t.Run("should return no messages after wait elapsed", func(t *testing.T) {
+		repo := &mock.Repository{
+			GetNewMessagesFunc: func(ctx context.Context, recipientUserName string, visibilityTimeout time.Duration, maxReceiveCount int) ([]model.Message, error) {
+				return nil, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		gotMessages, err := service.WaitForNewMessages(context.Background(), "recipient", time.Millisecond)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := len(gotMessages), 0; got != want {
+			t.Errorf("got %d messages, want %d", got, want)
+		}
+	})
+
+	t.Run("should stop waiting when context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		repo := &mock.Repository{
+			GetNewMessagesFunc: func(ctx context.Context, recipientUserName string, visibilityTimeout time.Duration, maxReceiveCount int) ([]model.Message, error) {
+				cancel()
+				return nil, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		_, err := service.WaitForNewMessages(ctx, "recipient", time.Hour)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestService_AcknowledgeMessages(t *testing.T) {
+	t.Run("should acknowledge messages", func(t *testing.T) {
+		wantMessageIDs := []string{"id"}
+
+		gotAcknowledgeMessagesCall := false
+		repo := &mock.Repository{
+			AcknowledgeMessagesFunc: func(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+				gotAcknowledgeMessagesCall = true
+				if diff := cmp.Diff(wantMessageIDs, messageIDs); diff != "" {
+					t.Errorf("message IDs mismatch (-want +got):\n%s", diff)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		if err := service.AcknowledgeMessages(context.Background(), wantMessageIDs); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantAcknowledgeMessagesCall := true
+		if got, want := gotAcknowledgeMessagesCall, wantAcknowledgeMessagesCall; got != want {
+			t.Errorf("got acknowledge messages call %v, want %v", got, want)
+		}
+	})
+
+	t.Run("should only acknowledge messages of the authenticated user", func(t *testing.T) {
+		repo := &mock.Repository{
+			AcknowledgeMessagesFunc: func(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+				wantRecipientUserName := "user"
+				if diff := cmp.Diff(&wantRecipientUserName, recipientUserName); diff != "" {
+					t.Errorf("recipient user name mismatch (-want +got):\n%s", diff)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		ctx := core.ContextWithUser(context.Background(), model.User{Name: "user"})
+		if err := service.AcknowledgeMessages(ctx, []string{"id"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestService_MarkMessagesUnfetched(t *testing.T) {
+	t.Run("should mark messages unfetched", func(t *testing.T) {
+		wantMessageIDs := []string{"id"}
+
+		gotMarkMessagesUnfetchedCall := false
+		repo := &mock.Repository{
+			MarkMessagesUnfetchedFunc: func(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+				gotMarkMessagesUnfetchedCall = true
+				if diff := cmp.Diff(wantMessageIDs, messageIDs); diff != "" {
+					t.Errorf("message IDs mismatch (-want +got):\n%s", diff)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		if err := service.MarkMessagesUnfetched(context.Background(), wantMessageIDs); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantMarkMessagesUnfetchedCall := true
+		if got, want := gotMarkMessagesUnfetchedCall, wantMarkMessagesUnfetchedCall; got != want {
+			t.Errorf("got mark messages unfetched call %v, want %v", got, want)
+		}
+	})
+}
+
+func TestService_CancelScheduledMessage(t *testing.T) {
+	t.Run("should cancel scheduled message", func(t *testing.T) {
+		wantMessageID := "id"
+
+		gotCancelScheduledMessageCall := false
+		repo := &mock.Repository{
+			CancelScheduledMessageFunc: func(ctx context.Context, senderUserName *string, messageID string) error {
+				gotCancelScheduledMessageCall = true
+				if got, want := messageID, wantMessageID; got != want {
+					t.Errorf("got message ID %q, want %q", got, want)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		if err := service.CancelScheduledMessage(context.Background(), wantMessageID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantCancelScheduledMessageCall := true
+		if got, want := gotCancelScheduledMessageCall, wantCancelScheduledMessageCall; got != want {
+			t.Errorf("got cancel scheduled message call %v, want %v", got, want)
+		}
+	})
+
+	t.Run("should only cancel messages sent by the authenticated user", func(t *testing.T) {
+		repo := &mock.Repository{
+			CancelScheduledMessageFunc: func(ctx context.Context, senderUserName *string, messageID string) error {
+				wantSenderUserName := "user"
+				if diff := cmp.Diff(&wantSenderUserName, senderUserName); diff != "" {
+					t.Errorf("sender user name mismatch (-want +got):\n%s", diff)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		ctx := core.ContextWithUser(context.Background(), model.User{Name: "user"})
+		if err := service.CancelScheduledMessage(ctx, "id"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
 	})
 }
 
@@ -92,7 +452,7 @@ func TestService_DeleteMessages(t *testing.T) {
 
 		gotDeleteMessagesCall := false
 		repo := &mock.Repository{
-			DeleteMessagesFunc: func(ctx context.Context, messageIDs []string) error {
+			DeleteMessagesFunc: func(ctx context.Context, recipientUserName *string, messageIDs []string) error {
 				gotDeleteMessagesCall = true
 				if diff := cmp.Diff(wantMessageIDs, messageIDs); diff != "" {
 					t.Errorf("message IDs mismatch (-want +got):\n%s", diff)
@@ -112,6 +472,261 @@ func TestService_DeleteMessages(t *testing.T) {
 			t.Errorf("got delete messages call %v, want %v", got, want)
 		}
 	})
+
+	t.Run("should only delete messages of the authenticated user", func(t *testing.T) {
+		repo := &mock.Repository{
+			DeleteMessagesFunc: func(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+				wantRecipientUserName := "user"
+				if diff := cmp.Diff(&wantRecipientUserName, recipientUserName); diff != "" {
+					t.Errorf("recipient user name mismatch (-want +got):\n%s", diff)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		ctx := core.ContextWithUser(context.Background(), model.User{Name: "user"})
+		if err := service.DeleteMessages(ctx, []string{"id"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should let admins delete messages of all users", func(t *testing.T) {
+		repo := &mock.Repository{
+			DeleteMessagesFunc: func(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+				if recipientUserName != nil {
+					t.Errorf("got recipient user name %q, want nil", *recipientUserName)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		ctx := core.ContextWithUser(context.Background(), model.User{Name: "admin", Admin: true})
+		if err := service.DeleteMessages(ctx, []string{"id"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should return forbidden error from repository", func(t *testing.T) {
+		repo := &mock.Repository{
+			DeleteMessagesFunc: func(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+				return model.ErrForbidden
+			},
+		}
+
+		service := core.NewService(repo)
+
+		ctx := core.ContextWithUser(context.Background(), model.User{Name: "user"})
+		err := service.DeleteMessages(ctx, []string{"id"})
+		if !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("got error %v, want %v", err, model.ErrForbidden)
+		}
+	})
+}
+
+func TestService_DeleteExistingMessages(t *testing.T) {
+	t.Run("should return deleted and missing messages", func(t *testing.T) {
+		wantMessageIDs := []string{"id1", "id2"}
+		wantResult := model.DeleteResult{Deleted: []string{"id1"}, Missing: []string{"id2"}}
+
+		repo := &mock.Repository{
+			DeleteExistingMessagesFunc: func(ctx context.Context, recipientUserName *string, messageIDs []string) (model.DeleteResult, error) {
+				if diff := cmp.Diff(wantMessageIDs, messageIDs); diff != "" {
+					t.Errorf("message IDs mismatch (-want +got):\n%s", diff)
+				}
+				return wantResult, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		gotResult, err := service.DeleteExistingMessages(context.Background(), wantMessageIDs)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantResult, gotResult); diff != "" {
+			t.Errorf("result mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestService_GetTrash(t *testing.T) {
+	t.Run("should get trash", func(t *testing.T) {
+		wantRecipientUserName := "recipient"
+		deletedAt := time.Now()
+		wantMessages := []model.Message{
+			{
+				ID:                "id",
+				RecipientUserName: wantRecipientUserName,
+				Content:           "content",
+				SentAt:            time.Now(),
+				DeletedAt:         &deletedAt,
+			},
+		}
+
+		repo := &mock.Repository{
+			GetTrashFunc: func(ctx context.Context, recipientUserName *string) ([]model.Message, error) {
+				if diff := cmp.Diff(&wantRecipientUserName, recipientUserName); diff != "" {
+					t.Errorf("recipient user name mismatch (-want +got):\n%s", diff)
+				}
+				return wantMessages, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		gotMessages, err := service.GetTrash(context.Background(), &wantRecipientUserName)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Errorf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestService_RestoreMessages(t *testing.T) {
+	t.Run("should restore messages", func(t *testing.T) {
+		wantMessageIDs := []string{"id"}
+
+		gotRestoreMessagesCall := false
+		repo := &mock.Repository{
+			RestoreMessagesFunc: func(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+				gotRestoreMessagesCall = true
+				if diff := cmp.Diff(wantMessageIDs, messageIDs); diff != "" {
+					t.Errorf("message IDs mismatch (-want +got):\n%s", diff)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		if err := service.RestoreMessages(context.Background(), wantMessageIDs); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRestoreMessagesCall := true
+		if got, want := gotRestoreMessagesCall, wantRestoreMessagesCall; got != want {
+			t.Errorf("got restore messages call %v, want %v", got, want)
+		}
+	})
+
+	t.Run("should only restore messages of the authenticated user", func(t *testing.T) {
+		repo := &mock.Repository{
+			RestoreMessagesFunc: func(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+				wantRecipientUserName := "user"
+				if diff := cmp.Diff(&wantRecipientUserName, recipientUserName); diff != "" {
+					t.Errorf("recipient user name mismatch (-want +got):\n%s", diff)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		ctx := core.ContextWithUser(context.Background(), model.User{Name: "user"})
+		if err := service.RestoreMessages(ctx, []string{"id"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestService_GetMessage(t *testing.T) {
+	t.Run("should get message", func(t *testing.T) {
+		wantMessage := model.Message{
+			ID:                "id",
+			RecipientUserName: "recipient",
+			Content:           "content",
+			SentAt:            time.Now(),
+		}
+
+		repo := &mock.Repository{
+			GetMessageFunc: func(ctx context.Context, messageID string) (model.Message, error) {
+				if got, want := messageID, wantMessage.ID; got != want {
+					t.Errorf("got message ID %q, want %q", got, want)
+				}
+				return wantMessage, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		gotMessage, err := service.GetMessage(context.Background(), wantMessage.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantMessage, gotMessage); diff != "" {
+			t.Errorf("message mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return not found error", func(t *testing.T) {
+		repo := &mock.Repository{
+			GetMessageFunc: func(ctx context.Context, messageID string) (model.Message, error) {
+				return model.Message{}, model.ErrNotFound
+			},
+		}
+
+		service := core.NewService(repo)
+
+		_, err := service.GetMessage(context.Background(), "id")
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("got error %v, want %v", err, model.ErrNotFound)
+		}
+	})
+
+	deliverAt := time.Now().Add(time.Hour)
+	scheduledMessage := model.Message{
+		ID:                "id",
+		SenderUserName:    "sender",
+		RecipientUserName: "recipient",
+		Content:           "content",
+		SentAt:            time.Now(),
+		DeliverAt:         &deliverAt,
+	}
+
+	t.Run("should return not found error if the message is scheduled and the user is not the sender", func(t *testing.T) {
+		repo := &mock.Repository{
+			GetMessageFunc: func(ctx context.Context, messageID string) (model.Message, error) {
+				return scheduledMessage, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		ctx := core.ContextWithUser(context.Background(), model.User{Name: "recipient"})
+		_, err := service.GetMessage(ctx, scheduledMessage.ID)
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("got error %v, want %v", err, model.ErrNotFound)
+		}
+	})
+
+	t.Run("should get a scheduled message if the user is the sender", func(t *testing.T) {
+		repo := &mock.Repository{
+			GetMessageFunc: func(ctx context.Context, messageID string) (model.Message, error) {
+				return scheduledMessage, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		ctx := core.ContextWithUser(context.Background(), model.User{Name: "sender"})
+		gotMessage, err := service.GetMessage(ctx, scheduledMessage.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(scheduledMessage, gotMessage); diff != "" {
+			t.Errorf("message mismatch (-want +got):\n%s", diff)
+		}
+	})
 }
 
 func TestService_GetAllMessages(t *testing.T) {
@@ -127,14 +742,145 @@ func TestService_GetAllMessages(t *testing.T) {
 		}
 
 		repo := &mock.Repository{
-			GetAllMessagesFunc: func(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error) {
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+				return model.MessagePage{Messages: wantMessages}, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		gotPage, err := service.GetAllMessages(context.Background(), core.MessageFilter{}, model.PageRequest{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantMessages, gotPage.Messages); diff != "" {
+			t.Errorf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should use default page limit", func(t *testing.T) {
+		wantCursor := &model.PageCursor{SentAt: time.Now(), ID: "id"}
+
+		repo := &mock.Repository{
+			GetAllMessagesFunc: func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+				if diff := cmp.Diff(model.PageRequest{Limit: core.DefaultPageLimit, Cursor: wantCursor}, page); diff != "" {
+					t.Errorf("page mismatch (-want +got):\n%s", diff)
+				}
+				return model.MessagePage{}, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		if _, err := service.GetAllMessages(context.Background(), core.MessageFilter{}, model.PageRequest{Cursor: wantCursor}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestService_SearchMessages(t *testing.T) {
+	t.Run("should search messages with default page limit", func(t *testing.T) {
+		wantPage := model.SearchPage{
+			Results: []model.SearchResult{
+				{
+					Message: model.Message{ID: "id", Content: "hello world", SentAt: time.Now()},
+					Rank:    0.5,
+					Snippet: "<mark>hello</mark> world",
+				},
+			},
+		}
+
+		repo := &mock.Repository{
+			SearchMessagesFunc: func(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error) {
+				if got, want := query, "hello"; got != want {
+					t.Errorf("got query %q, want %q", got, want)
+				}
+				if got, want := limit, core.DefaultPageLimit; got != want {
+					t.Errorf("got limit %d, want %d", got, want)
+				}
+				if got, want := offset, 20; got != want {
+					t.Errorf("got offset %d, want %d", got, want)
+				}
+				return wantPage, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		gotPage, err := service.SearchMessages(context.Background(), nil, "hello", 0, 20)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantPage, gotPage); diff != "" {
+			t.Errorf("page mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestService_GetSentMessages(t *testing.T) {
+	t.Run("should get sent messages", func(t *testing.T) {
+		wantMessages := []model.Message{
+			{
+				ID:                "id",
+				SenderUserName:    "sender",
+				RecipientUserName: "recipient",
+				Content:           "content",
+				SentAt:            time.Now(),
+			},
+		}
+
+		repo := &mock.Repository{
+			GetSentMessagesFunc: func(ctx context.Context, senderUserName string) ([]model.Message, error) {
+				if got, want := senderUserName, "sender"; got != want {
+					t.Errorf("got sender user name %q, want %q", got, want)
+				}
+				return wantMessages, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		gotMessages, err := service.GetSentMessages(context.Background(), "sender")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Errorf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestService_GetReceivedMessages(t *testing.T) {
+	t.Run("should get received messages after cursor", func(t *testing.T) {
+		wantCursor := "cursor"
+		wantMessages := []model.Message{
+			{
+				ID:                "id",
+				SenderUserName:    "sender",
+				RecipientUserName: "recipient",
+				Content:           "content",
+				SentAt:            time.Now(),
+			},
+		}
+
+		repo := &mock.Repository{
+			GetReceivedMessagesFunc: func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+				if got, want := recipientUserName, "recipient"; got != want {
+					t.Errorf("got recipient user name %q, want %q", got, want)
+				}
+				if diff := cmp.Diff(&wantCursor, afterCursor); diff != "" {
+					t.Errorf("cursor mismatch (-want +got):\n%s", diff)
+				}
 				return wantMessages, nil
 			},
 		}
 
 		service := core.NewService(repo)
 
-		gotMessages, err := service.GetAllMessages(context.Background(), nil, nil)
+		gotMessages, err := service.GetReceivedMessages(context.Background(), "recipient", &wantCursor, 10)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -143,4 +889,87 @@ func TestService_GetAllMessages(t *testing.T) {
 			t.Errorf("messages mismatch (-want +got):\n%s", diff)
 		}
 	})
+
+	t.Run("should use the default page limit if no limit is given", func(t *testing.T) {
+		repo := &mock.Repository{
+			GetReceivedMessagesFunc: func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+				if got, want := limit, core.DefaultPageLimit; got != want {
+					t.Errorf("got limit %d, want %d", got, want)
+				}
+				return nil, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		if _, err := service.GetReceivedMessages(context.Background(), "recipient", nil, 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestService_GetDeadLetters(t *testing.T) {
+	t.Run("should get dead letters", func(t *testing.T) {
+		wantRecipientUserName := "recipient"
+		wantDeadLetters := []model.DeadLetter{
+			{
+				Message: model.Message{
+					ID:                "id",
+					RecipientUserName: wantRecipientUserName,
+					Content:           "content",
+					SentAt:            time.Now(),
+					ReceiveCount:      5,
+				},
+				DeadLetteredAt: time.Now(),
+			},
+		}
+
+		repo := &mock.Repository{
+			GetDeadLettersFunc: func(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+				if diff := cmp.Diff(&wantRecipientUserName, recipientUserName); diff != "" {
+					t.Errorf("recipient user name mismatch (-want +got):\n%s", diff)
+				}
+				return wantDeadLetters, nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		gotDeadLetters, err := service.GetDeadLetters(context.Background(), &wantRecipientUserName)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantDeadLetters, gotDeadLetters); diff != "" {
+			t.Errorf("dead letters mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestService_RedriveDeadLetters(t *testing.T) {
+	t.Run("should redrive dead letters", func(t *testing.T) {
+		wantMessageIDs := []string{"id"}
+
+		gotRedriveDeadLettersCall := false
+		repo := &mock.Repository{
+			RedriveDeadLettersFunc: func(ctx context.Context, messageIDs []string) error {
+				gotRedriveDeadLettersCall = true
+				if diff := cmp.Diff(wantMessageIDs, messageIDs); diff != "" {
+					t.Errorf("message IDs mismatch (-want +got):\n%s", diff)
+				}
+				return nil
+			},
+		}
+
+		service := core.NewService(repo)
+
+		if err := service.RedriveDeadLetters(context.Background(), wantMessageIDs); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantRedriveDeadLettersCall := true
+		if got, want := gotRedriveDeadLettersCall, wantRedriveDeadLettersCall; got != want {
+			t.Errorf("got redrive dead letters call %v, want %v", got, want)
+		}
+	})
 }
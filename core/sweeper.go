@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Purger interface {
+	PurgeMessages(ctx context.Context, retentionPeriod, trashRetentionPeriod time.Duration, limit int) (int64, error)
+}
+
+// RateLimitPruner deletes the token buckets of clients that stopped sending.
+type RateLimitPruner interface {
+	PruneRateLimits(ctx context.Context, limit int) (int64, error)
+}
+
+// DefaultSweepInterval is the duration between two runs of the sweeper.
+const DefaultSweepInterval = time.Minute
+
+// DefaultSweepBatchSize is the maximum number of messages deleted in a single
+// statement, which keeps the sweeper from holding locks on large parts of the
+// table.
+const DefaultSweepBatchSize = 1000
+
+// DefaultTrashRetentionPeriod is the duration after which deleted messages are
+// removed from the trash for good.
+const DefaultTrashRetentionPeriod = 30 * 24 * time.Hour
+
+// Sweeper periodically deletes expired messages and dead letters, messages that
+// have been in the trash for longer than the trash retention period and, if a
+// retention period is configured, messages and dead letters sent before that
+// period.
+type Sweeper struct {
+	repo                 Purger
+	logger               *zap.Logger
+	interval             time.Duration
+	batchSize            int
+	retentionPeriod      time.Duration
+	trashRetentionPeriod time.Duration
+	rateLimitPruner      RateLimitPruner
+}
+
+type sweeperOptsFunc func(s *Sweeper)
+
+func NewSweeper(repo Purger, logger *zap.Logger, opts ...sweeperOptsFunc) *Sweeper {
+	s := &Sweeper{
+		repo:                 repo,
+		logger:               logger,
+		interval:             DefaultSweepInterval,
+		batchSize:            DefaultSweepBatchSize,
+		trashRetentionPeriod: DefaultTrashRetentionPeriod,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func SweeperInterval(interval time.Duration) sweeperOptsFunc {
+	return func(s *Sweeper) {
+		s.interval = interval
+	}
+}
+
+// SweeperBatchSize sets the maximum number of messages deleted in a single
+// statement. Sizes that are not positive are ignored, as a sweep would never
+// finish with them.
+func SweeperBatchSize(batchSize int) sweeperOptsFunc {
+	return func(s *Sweeper) {
+		if batchSize > 0 {
+			s.batchSize = batchSize
+		}
+	}
+}
+
+// SweeperRetentionPeriod sets the age after which messages are deleted
+// regardless of their expiry. A value of zero keeps messages until they expire.
+func SweeperRetentionPeriod(retentionPeriod time.Duration) sweeperOptsFunc {
+	return func(s *Sweeper) {
+		s.retentionPeriod = retentionPeriod
+	}
+}
+
+// SweeperTrashRetentionPeriod sets the duration after which deleted messages
+// are removed from the trash. A value of zero keeps them until they expire.
+func SweeperTrashRetentionPeriod(trashRetentionPeriod time.Duration) sweeperOptsFunc {
+	return func(s *Sweeper) {
+		s.trashRetentionPeriod = trashRetentionPeriod
+	}
+}
+
+// SweeperRateLimitPruner makes the sweeper also delete the token buckets that
+// have been refilled completely.
+func SweeperRateLimitPruner(rateLimitPruner RateLimitPruner) sweeperOptsFunc {
+	return func(s *Sweeper) {
+		s.rateLimitPruner = rateLimitPruner
+	}
+}
+
+// Run sweeps once per interval until the context is canceled.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.Sweep(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.logger.Error("failed to sweep messages", zap.Error(err))
+			}
+			if purged > 0 {
+				s.logger.Info("swept messages", zap.Int64("purged", purged))
+			}
+
+			pruned, err := s.PruneRateLimits(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.logger.Error("failed to prune rate limits", zap.Error(err))
+			}
+			if pruned > 0 {
+				s.logger.Info("pruned rate limits", zap.Int64("pruned", pruned))
+			}
+		}
+	}
+}
+
+// Sweep deletes messages in batches until a batch comes back short and returns
+// the number of deleted messages.
+func (s *Sweeper) Sweep(ctx context.Context) (int64, error) {
+	var total int64
+	for {
+		purged, err := s.repo.PurgeMessages(ctx, s.retentionPeriod, s.trashRetentionPeriod, s.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("purge messages: %w", err)
+		}
+
+		total += purged
+		if purged < int64(s.batchSize) {
+			return total, nil
+		}
+	}
+}
+
+// PruneRateLimits deletes full token buckets in batches until a batch comes
+// back short and returns the number of deleted buckets. It does nothing if the
+// sweeper has no rate limit pruner.
+func (s *Sweeper) PruneRateLimits(ctx context.Context) (int64, error) {
+	if s.rateLimitPruner == nil {
+		return 0, nil
+	}
+
+	var total int64
+	for {
+		pruned, err := s.rateLimitPruner.PruneRateLimits(ctx, s.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("prune rate limits: %w", err)
+		}
+
+		total += pruned
+		if pruned < int64(s.batchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/mock"
+	"go.uber.org/zap"
+)
+
+func TestSweeper_Sweep(t *testing.T) {
+	t.Run("should purge messages in batches until a batch is short", func(t *testing.T) {
+		batches := []int64{2, 2, 1}
+		gotPurgeMessagesCalls := 0
+		repo := &mock.Repository{
+			PurgeMessagesFunc: func(ctx context.Context, retentionPeriod, trashRetentionPeriod time.Duration, limit int) (int64, error) {
+				if got, want := retentionPeriod, time.Hour; got != want {
+					t.Errorf("got retention period %v, want %v", got, want)
+				}
+				if got, want := trashRetentionPeriod, core.DefaultTrashRetentionPeriod; got != want {
+					t.Errorf("got trash retention period %v, want %v", got, want)
+				}
+				if got, want := limit, 2; got != want {
+					t.Errorf("got limit %d, want %d", got, want)
+				}
+				purged := batches[gotPurgeMessagesCalls]
+				gotPurgeMessagesCalls++
+				return purged, nil
+			},
+		}
+
+		sweeper := core.NewSweeper(repo, zap.NewNop(), core.SweeperBatchSize(2), core.SweeperRetentionPeriod(time.Hour))
+
+		gotPurged, err := sweeper.Sweep(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotPurged, int64(5); got != want {
+			t.Errorf("got purged %d, want %d", got, want)
+		}
+
+		if got, want := gotPurgeMessagesCalls, 3; got != want {
+			t.Errorf("got purge messages calls %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should keep the default batch size if the batch size is not positive", func(t *testing.T) {
+		repo := &mock.Repository{
+			PurgeMessagesFunc: func(ctx context.Context, retentionPeriod, trashRetentionPeriod time.Duration, limit int) (int64, error) {
+				if got, want := limit, core.DefaultSweepBatchSize; got != want {
+					t.Errorf("got limit %d, want %d", got, want)
+				}
+				return 0, nil
+			},
+		}
+
+		sweeper := core.NewSweeper(repo, zap.NewNop(), core.SweeperBatchSize(0))
+
+		if _, err := sweeper.Sweep(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should return error if purge fails", func(t *testing.T) {
+		wantErr := errors.New("error")
+		repo := &mock.Repository{
+			PurgeMessagesFunc: func(ctx context.Context, retentionPeriod, trashRetentionPeriod time.Duration, limit int) (int64, error) {
+				return 0, wantErr
+			},
+		}
+
+		sweeper := core.NewSweeper(repo, zap.NewNop())
+
+		_, err := sweeper.Sweep(context.Background())
+		if !errors.Is(err, wantErr) {
+			t.Errorf("got error %v, want %v", err, wantErr)
+		}
+	})
+}
+
+func TestSweeper_PruneRateLimits(t *testing.T) {
+	t.Run("should prune rate limits in batches until a batch is short", func(t *testing.T) {
+		batches := []int64{2, 0}
+		gotPruneRateLimitsCalls := 0
+		rateLimiter := &mock.RateLimiter{
+			PruneRateLimitsFunc: func(ctx context.Context, limit int) (int64, error) {
+				if got, want := limit, 2; got != want {
+					t.Errorf("got limit %d, want %d", got, want)
+				}
+				pruned := batches[gotPruneRateLimitsCalls]
+				gotPruneRateLimitsCalls++
+				return pruned, nil
+			},
+		}
+
+		sweeper := core.NewSweeper(&mock.Repository{}, zap.NewNop(), core.SweeperBatchSize(2), core.SweeperRateLimitPruner(rateLimiter))
+
+		gotPruned, err := sweeper.PruneRateLimits(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotPruned, int64(2); got != want {
+			t.Errorf("got pruned %d, want %d", got, want)
+		}
+
+		if got, want := gotPruneRateLimitsCalls, 2; got != want {
+			t.Errorf("got prune rate limits calls %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should not prune without a rate limit pruner", func(t *testing.T) {
+		sweeper := core.NewSweeper(&mock.Repository{}, zap.NewNop())
+
+		gotPruned, err := sweeper.PruneRateLimits(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotPruned, int64(0); got != want {
+			t.Errorf("got pruned %d, want %d", got, want)
+		}
+	})
+}
+
+func TestSweeper_Run(t *testing.T) {
+	t.Run("should stop when context is canceled", func(t *testing.T) {
+		swept := make(chan struct{}, 1)
+		repo := &mock.Repository{
+			PurgeMessagesFunc: func(ctx context.Context, retentionPeriod, trashRetentionPeriod time.Duration, limit int) (int64, error) {
+				select {
+				case swept <- struct{}{}:
+				default:
+				}
+				return 0, nil
+			},
+		}
+
+		sweeper := core.NewSweeper(repo, zap.NewNop(), core.SweeperInterval(time.Millisecond))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			sweeper.Run(ctx)
+			close(done)
+		}()
+
+		select {
+		case <-swept:
+		case <-time.After(time.Second):
+			t.Fatal("sweeper did not sweep")
+		}
+
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("sweeper did not stop")
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"context"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+)
+
+type userContextKey struct{}
+
+// ContextWithUser returns a context carrying the authenticated user.
+func ContextWithUser(ctx context.Context, user model.User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the authenticated user of the request. It reports
+// false if the request has not been authenticated.
+func UserFromContext(ctx context.Context) (model.User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(model.User)
+	return user, ok
+}
+
+// RestrictedUserName returns the name of the user whose messages the request
+// may read and change, or nil if it may access the messages of all users.
+// Requests that have not been authenticated and requests of admins are not
+// restricted.
+func RestrictedUserName(ctx context.Context) *string {
+	user, ok := UserFromContext(ctx)
+	if !ok || user.Admin {
+		return nil
+	}
+	return &user.Name
+}
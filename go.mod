@@ -4,8 +4,10 @@ go 1.20
 
 require (
 	github.com/docker/go-connections v0.4.0
+	github.com/golang-jwt/jwt/v4 v4.5.2
 	github.com/google/go-cmp v0.5.9
 	github.com/google/uuid v1.3.0
+	github.com/gorilla/websocket v1.5.0
 	go.uber.org/zap v1.24.0
 )
 
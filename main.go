@@ -7,11 +7,17 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/RichterMaximilian/osttra-coding-assignment/api"
+	"github.com/RichterMaximilian/osttra-coding-assignment/auth"
 	"github.com/RichterMaximilian/osttra-coding-assignment/core"
 	"github.com/RichterMaximilian/osttra-coding-assignment/migrate"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
 	"github.com/RichterMaximilian/osttra-coding-assignment/postgres"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/kelseyhightower/envconfig"
@@ -19,10 +25,69 @@ import (
 )
 
 type Config struct {
+	Server struct {
+		ShutdownTimeout time.Duration `envconfig:"SERVER_SHUTDOWN_TIMEOUT" default:"30s"`
+	}
 	DB struct {
 		ConnStr       string `envconfig:"DB_CONN" required:"true"`
 		MigrationsDir string `envconfig:"DB_MIGRATIONS_DIR" default:"file://migrations"`
 	}
+	Messages struct {
+		VisibilityTimeout time.Duration `envconfig:"MESSAGES_VISIBILITY_TIMEOUT" default:"30s"`
+		MaxReceiveCount   int           `envconfig:"MESSAGES_MAX_RECEIVE_COUNT" default:"5"`
+		IdempotencyWindow time.Duration `envconfig:"MESSAGES_IDEMPOTENCY_WINDOW" default:"24h"`
+	}
+	Quota struct {
+		MaxUnfetchedMessages int   `envconfig:"QUOTA_MAX_UNFETCHED_MESSAGES" default:"10000"`
+		MaxBytes             int64 `envconfig:"QUOTA_MAX_BYTES" default:"104857600"`
+	}
+	Retention struct {
+		Period        time.Duration `envconfig:"RETENTION_PERIOD" default:"0"`
+		TrashPeriod   time.Duration `envconfig:"RETENTION_TRASH_PERIOD" default:"720h"`
+		SweepInterval time.Duration `envconfig:"RETENTION_SWEEP_INTERVAL" default:"1m"`
+		BatchSize     int           `envconfig:"RETENTION_BATCH_SIZE" default:"1000"`
+	}
+	Auth struct {
+		HMACSecret          string        `envconfig:"AUTH_HMAC_SECRET"`
+		JWKSFile            string        `envconfig:"AUTH_JWKS_FILE"`
+		JWKSURL             string        `envconfig:"AUTH_JWKS_URL"`
+		JWKSRefreshInterval time.Duration `envconfig:"AUTH_JWKS_REFRESH_INTERVAL" default:"1h"`
+		Issuer              string        `envconfig:"AUTH_ISSUER"`
+		Audience            string        `envconfig:"AUTH_AUDIENCE"`
+		UserClaim           string        `envconfig:"AUTH_USER_CLAIM" default:"sub"`
+		RolesClaim          string        `envconfig:"AUTH_ROLES_CLAIM" default:"roles"`
+		AdminRole           string        `envconfig:"AUTH_ADMIN_ROLE" default:"admin"`
+	}
+	RateLimit struct {
+		Default rateLimitSpec            `envconfig:"RATE_LIMIT_DEFAULT" default:"600/1m"`
+		Routes  map[string]rateLimitSpec `envconfig:"RATE_LIMIT_ROUTES" default:"POST /messages:60/1m,POST /messages/batch:10/1m"`
+		IP      rateLimitSpec            `envconfig:"RATE_LIMIT_IP" default:"1200/1m"`
+		Shared  bool                     `envconfig:"RATE_LIMIT_SHARED" default:"false"`
+	}
+}
+
+// rateLimitSpec is a rate limit in the form "<limit>/<period>", e.g. "60/1m".
+// A limit of zero disables rate limiting.
+type rateLimitSpec model.RateLimit
+
+func (s *rateLimitSpec) Decode(value string) error {
+	limitRaw, periodRaw, ok := strings.Cut(value, "/")
+	if !ok {
+		return fmt.Errorf("rate limit %q: missing period", value)
+	}
+
+	limit, err := strconv.Atoi(limitRaw)
+	if err != nil || limit < 0 {
+		return fmt.Errorf("rate limit %q: limit must be a non-negative integer", value)
+	}
+
+	period, err := time.ParseDuration(periodRaw)
+	if err != nil || period <= 0 {
+		return fmt.Errorf("rate limit %q: period must be a positive duration", value)
+	}
+
+	*s = rateLimitSpec{Limit: limit, Period: period}
+	return nil
 }
 
 func main() {
@@ -49,14 +114,111 @@ func main() {
 	defer logger.Sync()
 
 	repository := postgres.NewRepository(pool)
+	subscriber := postgres.NewSubscriber(cfg.DB.ConnStr, logger)
+
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+	backgroundDone := &sync.WaitGroup{}
 
-	service := core.NewService(repository)
-	router := api.NewRouter(service, logger)
+	backgroundDone.Add(1)
+	go func() {
+		defer backgroundDone.Done()
+		subscriber.Run(backgroundCtx)
+	}()
+
+	service := core.NewService(
+		repository,
+		core.ServiceVisibilityTimeout(cfg.Messages.VisibilityTimeout),
+		core.ServiceMaxReceiveCount(cfg.Messages.MaxReceiveCount),
+		core.ServiceIdempotencyWindow(cfg.Messages.IdempotencyWindow),
+		core.ServiceChangeFeed(subscriber),
+		core.ServiceQuota(model.Quota{
+			MaxUnfetchedMessages: cfg.Quota.MaxUnfetchedMessages,
+			MaxBytes:             cfg.Quota.MaxBytes,
+		}),
+	)
+
+	var keySet auth.KeySet
+	if cfg.Auth.JWKSFile != "" {
+		keySet, err = auth.LoadKeySetFile(cfg.Auth.JWKSFile)
+		if err != nil {
+			log.Fatalf("load JWKS file: %v", err)
+		}
+	}
+	if cfg.Auth.JWKSURL != "" {
+		remoteKeySet := auth.NewRemoteKeySet(cfg.Auth.JWKSURL, logger, auth.RemoteKeySetRefreshInterval(cfg.Auth.JWKSRefreshInterval))
+		if err := remoteKeySet.Refresh(ctx); err != nil {
+			log.Fatalf("fetch JWKS: %v", err)
+		}
+		keySet = remoteKeySet
+
+		backgroundDone.Add(1)
+		go func() {
+			defer backgroundDone.Done()
+			remoteKeySet.Run(backgroundCtx)
+		}()
+	}
+
+	authenticator := auth.NewJWTAuthenticator(
+		auth.JWTAuthenticatorHMACSecret([]byte(cfg.Auth.HMACSecret)),
+		auth.JWTAuthenticatorKeySet(keySet),
+		auth.JWTAuthenticatorIssuer(cfg.Auth.Issuer),
+		auth.JWTAuthenticatorAudience(cfg.Auth.Audience),
+		auth.JWTAuthenticatorUserClaim(cfg.Auth.UserClaim),
+		auth.JWTAuthenticatorRolesClaim(cfg.Auth.RolesClaim),
+		auth.JWTAuthenticatorAdminRole(cfg.Auth.AdminRole),
+	)
+
+	var rateLimiter api.RateLimiter = core.NewMemoryRateLimiter()
+	// Buckets in memory are pruned by the rate limiter itself.
+	var rateLimitPruner core.RateLimitPruner
+	if cfg.RateLimit.Shared {
+		sharedRateLimiter := postgres.NewRateLimiter(pool)
+		rateLimiter = sharedRateLimiter
+		rateLimitPruner = sharedRateLimiter
+	}
+
+	rateLimits := api.RateLimits{
+		Default: model.RateLimit(cfg.RateLimit.Default),
+		Routes:  make(map[string]model.RateLimit, len(cfg.RateLimit.Routes)),
+		IP:      model.RateLimit(cfg.RateLimit.IP),
+	}
+	for route, limit := range cfg.RateLimit.Routes {
+		rateLimits.Routes[route] = model.RateLimit(limit)
+	}
+
+	// Open streams end when the server shuts down, while running requests may
+	// finish.
+	shutdown := make(chan struct{})
+	router := api.NewRouter(
+		service,
+		logger,
+		api.RouterAuthenticator(authenticator),
+		api.RouterRateLimiter(rateLimiter, rateLimits),
+		api.RouterShutdown(shutdown),
+	)
+
+	sweeper := core.NewSweeper(
+		repository,
+		logger,
+		core.SweeperInterval(cfg.Retention.SweepInterval),
+		core.SweeperBatchSize(cfg.Retention.BatchSize),
+		core.SweeperRetentionPeriod(cfg.Retention.Period),
+		core.SweeperTrashRetentionPeriod(cfg.Retention.TrashPeriod),
+		core.SweeperRateLimitPruner(rateLimitPruner),
+	)
+	backgroundDone.Add(1)
+	go func() {
+		defer backgroundDone.Done()
+		sweeper.Run(backgroundCtx)
+	}()
 
 	srv := &http.Server{
 		Addr:    ":8080",
 		Handler: router,
 	}
+	srv.RegisterOnShutdown(func() {
+		close(shutdown)
+	})
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGTERM)
@@ -71,10 +233,16 @@ func main() {
 	// Wait for SIGTERM
 	<-signals
 	// Shutdown server gracefully
-	if err := srv.Shutdown(context.Background()); err != nil {
-		log.Fatalf("failed to shutdown server: %v", err)
-		os.Exit(1)
+	shutdownCtx, cancelShutdown := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// The background jobs are stopped all the same.
+		logger.Error("failed to shutdown server", zap.Error(err))
 	}
+	// Stop background jobs, which cancels a sweep in progress, and wait for them
+	// to return
+	stopBackground()
+	backgroundDone.Wait()
 	os.Exit(0)
 }
 
@@ -84,5 +252,17 @@ func parseConfig() (Config, error) {
 		return cfg, fmt.Errorf("unable to process config: %w", err)
 	}
 
+	if cfg.Auth.HMACSecret == "" && cfg.Auth.JWKSFile == "" && cfg.Auth.JWKSURL == "" {
+		return cfg, fmt.Errorf("one of AUTH_HMAC_SECRET, AUTH_JWKS_FILE and AUTH_JWKS_URL is required")
+	}
+
+	if cfg.Auth.JWKSFile != "" && cfg.Auth.JWKSURL != "" {
+		return cfg, fmt.Errorf("AUTH_JWKS_FILE and AUTH_JWKS_URL cannot both be set")
+	}
+
+	if cfg.Retention.BatchSize <= 0 {
+		return cfg, fmt.Errorf("RETENTION_BATCH_SIZE needs to be positive")
+	}
+
 	return cfg, nil
 }
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS messages_sent_at_idx;
+
+CREATE INDEX IF NOT EXISTS messages_sent_at_id_idx ON messages (sent_at, id);
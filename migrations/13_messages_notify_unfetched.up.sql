@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS messages_notify_unfetched ON messages;
+CREATE TRIGGER messages_notify_unfetched
+	AFTER UPDATE OF fetched_at ON messages
+	FOR EACH ROW
+	WHEN (OLD.fetched_at IS NOT NULL AND NEW.fetched_at IS NULL)
+	EXECUTE FUNCTION notify_message_inserted();
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id text NOT NULL PRIMARY KEY CHECK (id <> ''),
+    user_name text NOT NULL CHECK (user_name <> ''),
+    scopes text[] NOT NULL,
+    key_hash bytea NOT NULL,
+    created_at timestamptz NOT NULL,
+    rotated_at timestamptz,
+    revoked_at timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_keys_key_hash_idx ON api_keys (key_hash);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS quotas (
+    user_name text NOT NULL PRIMARY KEY CHECK (user_name <> ''),
+    max_unfetched_messages integer CHECK (max_unfetched_messages >= 0),
+    max_bytes bigint CHECK (max_bytes >= 0)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE dead_letters ADD COLUMN IF NOT EXISTS deliver_at timestamptz;
+ALTER TABLE dead_letters ADD COLUMN IF NOT EXISTS expires_at timestamptz;
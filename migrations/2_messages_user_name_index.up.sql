@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS messages_user_name_new_idx ON messages (user_name, sent_at) WHERE fetched_at IS NULL;
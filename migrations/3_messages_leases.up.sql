@@ -0,0 +1,13 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS lease_expires_at timestamptz,
+    ADD COLUMN IF NOT EXISTS receive_count integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS acknowledged_at timestamptz;
+
+-- Messages fetched before leases existed were considered processed.
+UPDATE messages
+SET acknowledged_at = fetched_at,
+    receive_count = 1
+WHERE fetched_at IS NOT NULL;
+
+DROP INDEX IF EXISTS messages_user_name_new_idx;
+CREATE INDEX IF NOT EXISTS messages_user_name_unacknowledged_idx ON messages (user_name, sent_at) WHERE acknowledged_at IS NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id text NOT NULL PRIMARY KEY CHECK (id <> ''),
+    user_name text NOT NULL CHECK (user_name <> ''),
+    content text NOT NULL,
+    sent_at timestamptz NOT NULL,
+    fetched_at timestamptz,
+    receive_count integer NOT NULL,
+    dead_lettered_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS dead_letters_user_name_idx ON dead_letters (user_name, dead_lettered_at);
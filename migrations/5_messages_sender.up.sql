@@ -0,0 +1,4 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS sender_user_name text NOT NULL DEFAULT '';
+ALTER TABLE dead_letters ADD COLUMN IF NOT EXISTS sender_user_name text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS messages_sender_user_name_idx ON messages (sender_user_name, sent_at);
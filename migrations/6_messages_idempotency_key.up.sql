@@ -0,0 +1,3 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS idempotency_key text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS messages_sender_idempotency_key_idx ON messages (sender_user_name, idempotency_key) WHERE idempotency_key IS NOT NULL;
@@ -0,0 +1,14 @@
+CREATE OR REPLACE FUNCTION notify_message_inserted() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify('messages_inserted', json_build_object(
+		'message_id', NEW.id,
+		'recipient_user_name', NEW.user_name
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS messages_notify_inserted ON messages;
+CREATE TRIGGER messages_notify_inserted
+	AFTER INSERT ON messages
+	FOR EACH ROW EXECUTE FUNCTION notify_message_inserted();
@@ -0,0 +1,15 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+)
+
+type Authenticator struct {
+	AuthenticateFunc func(ctx context.Context, token string) (model.User, error)
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (model.User, error) {
+	return a.AuthenticateFunc(ctx, token)
+}
@@ -0,0 +1,15 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+)
+
+type ChangeFeed struct {
+	SubscribeFunc func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent
+}
+
+func (c *ChangeFeed) Subscribe(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+	return c.SubscribeFunc(ctx, recipientUserName)
+}
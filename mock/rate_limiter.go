@@ -0,0 +1,20 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+)
+
+type RateLimiter struct {
+	TakeFunc            func(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitResult, error)
+	PruneRateLimitsFunc func(ctx context.Context, limit int) (int64, error)
+}
+
+func (l *RateLimiter) Take(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitResult, error) {
+	return l.TakeFunc(ctx, key, limit)
+}
+
+func (l *RateLimiter) PruneRateLimits(ctx context.Context, limit int) (int64, error) {
+	return l.PruneRateLimitsFunc(ctx, limit)
+}
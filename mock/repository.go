@@ -2,29 +2,136 @@ package mock
 
 import (
 	"context"
+	"time"
 
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
 	"github.com/RichterMaximilian/osttra-coding-assignment/model"
 )
 
 type Repository struct {
-	InsertMessageFunc  func(ctx context.Context, message model.Message) error
-	GetNewMessagesFunc func(ctx context.Context) ([]model.Message, error)
-	DeleteMessagesFunc func(ctx context.Context, messageIDs []string) error
-	GetAllMessagesFunc func(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error)
+	InsertMessageFunc          func(ctx context.Context, message model.Message, idempotencyWindow time.Duration) (string, error)
+	GetIdempotentMessageIDFunc func(ctx context.Context, senderUserName, idempotencyKey string, idempotencyWindow time.Duration) (string, error)
+	InsertMessagesFunc         func(ctx context.Context, messages []model.Message) error
+	GetNewMessagesFunc         func(ctx context.Context, recipientUserName string, visibilityTimeout time.Duration, maxReceiveCount int) ([]model.Message, error)
+	AcknowledgeMessagesFunc    func(ctx context.Context, recipientUserName *string, messageIDs []string) error
+	MarkMessagesUnfetchedFunc  func(ctx context.Context, recipientUserName *string, messageIDs []string) error
+	CancelScheduledMessageFunc func(ctx context.Context, senderUserName *string, messageID string) error
+	DeleteMessagesFunc         func(ctx context.Context, recipientUserName *string, messageIDs []string) error
+	DeleteExistingMessagesFunc func(ctx context.Context, recipientUserName *string, messageIDs []string) (model.DeleteResult, error)
+	GetTrashFunc               func(ctx context.Context, recipientUserName *string) ([]model.Message, error)
+	RestoreMessagesFunc        func(ctx context.Context, recipientUserName *string, messageIDs []string) error
+	GetMessageFunc             func(ctx context.Context, messageID string) (model.Message, error)
+	GetAllMessagesFunc         func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error)
+	SearchMessagesFunc         func(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error)
+	GetSentMessagesFunc        func(ctx context.Context, senderUserName string) ([]model.Message, error)
+	PurgeMessagesFunc          func(ctx context.Context, retentionPeriod, trashRetentionPeriod time.Duration, limit int) (int64, error)
+	GetReceivedMessagesFunc    func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error)
+	GetDeadLettersFunc         func(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error)
+	RedriveDeadLettersFunc     func(ctx context.Context, messageIDs []string) error
+	InsertAPIKeyFunc           func(ctx context.Context, apiKey model.APIKey, keyHash []byte) error
+	GetAPIKeyByHashFunc        func(ctx context.Context, keyHash []byte) (model.APIKey, error)
+	GetAPIKeysFunc             func(ctx context.Context) ([]model.APIKey, error)
+	RevokeAPIKeyFunc           func(ctx context.Context, apiKeyID string, revokedAt time.Time) error
+	RotateAPIKeyFunc           func(ctx context.Context, apiKeyID string, keyHash []byte, rotatedAt time.Time) (model.APIKey, error)
+	GetUsagesFunc              func(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error)
 }
 
-func (r *Repository) InsertMessage(ctx context.Context, message model.Message) error {
-	return r.InsertMessageFunc(ctx, message)
+func (r *Repository) InsertMessage(ctx context.Context, message model.Message, idempotencyWindow time.Duration) (string, error) {
+	return r.InsertMessageFunc(ctx, message, idempotencyWindow)
 }
 
-func (r *Repository) GetNewMessages(ctx context.Context) ([]model.Message, error) {
-	return r.GetNewMessagesFunc(ctx)
+func (r *Repository) GetIdempotentMessageID(ctx context.Context, senderUserName, idempotencyKey string, idempotencyWindow time.Duration) (string, error) {
+	return r.GetIdempotentMessageIDFunc(ctx, senderUserName, idempotencyKey, idempotencyWindow)
 }
 
-func (r *Repository) DeleteMessages(ctx context.Context, messageIDs []string) error {
-	return r.DeleteMessagesFunc(ctx, messageIDs)
+func (r *Repository) InsertMessages(ctx context.Context, messages []model.Message) error {
+	return r.InsertMessagesFunc(ctx, messages)
 }
 
-func (r *Repository) GetAllMessages(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error) {
-	return r.GetAllMessagesFunc(ctx, startCursor, endCursor)
+func (r *Repository) GetNewMessages(ctx context.Context, recipientUserName string, visibilityTimeout time.Duration, maxReceiveCount int) ([]model.Message, error) {
+	return r.GetNewMessagesFunc(ctx, recipientUserName, visibilityTimeout, maxReceiveCount)
+}
+
+func (r *Repository) AcknowledgeMessages(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+	return r.AcknowledgeMessagesFunc(ctx, recipientUserName, messageIDs)
+}
+
+func (r *Repository) MarkMessagesUnfetched(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+	return r.MarkMessagesUnfetchedFunc(ctx, recipientUserName, messageIDs)
+}
+
+func (r *Repository) CancelScheduledMessage(ctx context.Context, senderUserName *string, messageID string) error {
+	return r.CancelScheduledMessageFunc(ctx, senderUserName, messageID)
+}
+
+func (r *Repository) DeleteMessages(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+	return r.DeleteMessagesFunc(ctx, recipientUserName, messageIDs)
+}
+
+func (r *Repository) DeleteExistingMessages(ctx context.Context, recipientUserName *string, messageIDs []string) (model.DeleteResult, error) {
+	return r.DeleteExistingMessagesFunc(ctx, recipientUserName, messageIDs)
+}
+
+func (r *Repository) GetTrash(ctx context.Context, recipientUserName *string) ([]model.Message, error) {
+	return r.GetTrashFunc(ctx, recipientUserName)
+}
+
+func (r *Repository) RestoreMessages(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+	return r.RestoreMessagesFunc(ctx, recipientUserName, messageIDs)
+}
+
+func (r *Repository) GetMessage(ctx context.Context, messageID string) (model.Message, error) {
+	return r.GetMessageFunc(ctx, messageID)
+}
+
+func (r *Repository) GetAllMessages(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+	return r.GetAllMessagesFunc(ctx, filter, page)
+}
+
+func (r *Repository) SearchMessages(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error) {
+	return r.SearchMessagesFunc(ctx, recipientUserName, query, limit, offset)
+}
+
+func (r *Repository) GetSentMessages(ctx context.Context, senderUserName string) ([]model.Message, error) {
+	return r.GetSentMessagesFunc(ctx, senderUserName)
+}
+
+func (r *Repository) PurgeMessages(ctx context.Context, retentionPeriod, trashRetentionPeriod time.Duration, limit int) (int64, error) {
+	return r.PurgeMessagesFunc(ctx, retentionPeriod, trashRetentionPeriod, limit)
+}
+
+func (r *Repository) GetReceivedMessages(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+	return r.GetReceivedMessagesFunc(ctx, recipientUserName, afterCursor, limit)
+}
+
+func (r *Repository) GetDeadLetters(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+	return r.GetDeadLettersFunc(ctx, recipientUserName)
+}
+
+func (r *Repository) RedriveDeadLetters(ctx context.Context, messageIDs []string) error {
+	return r.RedriveDeadLettersFunc(ctx, messageIDs)
+}
+
+func (r *Repository) InsertAPIKey(ctx context.Context, apiKey model.APIKey, keyHash []byte) error {
+	return r.InsertAPIKeyFunc(ctx, apiKey, keyHash)
+}
+
+func (r *Repository) GetAPIKeyByHash(ctx context.Context, keyHash []byte) (model.APIKey, error) {
+	return r.GetAPIKeyByHashFunc(ctx, keyHash)
+}
+
+func (r *Repository) GetAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	return r.GetAPIKeysFunc(ctx)
+}
+
+func (r *Repository) RevokeAPIKey(ctx context.Context, apiKeyID string, revokedAt time.Time) error {
+	return r.RevokeAPIKeyFunc(ctx, apiKeyID, revokedAt)
+}
+
+func (r *Repository) RotateAPIKey(ctx context.Context, apiKeyID string, keyHash []byte, rotatedAt time.Time) (model.APIKey, error) {
+	return r.RotateAPIKeyFunc(ctx, apiKeyID, keyHash, rotatedAt)
+}
+
+func (r *Repository) GetUsages(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error) {
+	return r.GetUsagesFunc(ctx, recipientUserNames, defaultQuota)
 }
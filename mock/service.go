@@ -2,29 +2,136 @@ package mock
 
 import (
 	"context"
+	"time"
 
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
 	"github.com/RichterMaximilian/osttra-coding-assignment/model"
 )
 
 type Service struct {
-	SubmitMessageFunc    func(ctx context.Context, recipientUserName, messageContent string) (string, error)
-	FetchNewMessagesFunc func(ctx context.Context) ([]model.Message, error)
-	DeleteMessagesFunc   func(ctx context.Context, messageIDs []string) error
-	GetAllMessagesFunc   func(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error)
+	SubmitMessageFunc          func(ctx context.Context, submission model.MessageSubmission) (string, error)
+	SubmitMessagesFunc         func(ctx context.Context, submissions []model.MessageSubmission) ([]string, error)
+	FetchNewMessagesFunc       func(ctx context.Context, recipientUserName string) ([]model.Message, error)
+	WaitForNewMessagesFunc     func(ctx context.Context, recipientUserName string, wait time.Duration) ([]model.Message, error)
+	SubscribeNewMessagesFunc   func(ctx context.Context, recipientUserName string) <-chan model.MessageEvent
+	AcknowledgeMessagesFunc    func(ctx context.Context, messageIDs []string) error
+	MarkMessagesUnfetchedFunc  func(ctx context.Context, messageIDs []string) error
+	CancelScheduledMessageFunc func(ctx context.Context, messageID string) error
+	DeleteMessagesFunc         func(ctx context.Context, messageIDs []string) error
+	DeleteExistingMessagesFunc func(ctx context.Context, messageIDs []string) (model.DeleteResult, error)
+	GetTrashFunc               func(ctx context.Context, recipientUserName *string) ([]model.Message, error)
+	RestoreMessagesFunc        func(ctx context.Context, messageIDs []string) error
+	GetMessageFunc             func(ctx context.Context, messageID string) (model.Message, error)
+	GetAllMessagesFunc         func(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error)
+	SearchMessagesFunc         func(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error)
+	GetSentMessagesFunc        func(ctx context.Context, senderUserName string) ([]model.Message, error)
+	GetReceivedMessagesFunc    func(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error)
+	GetDeadLettersFunc         func(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error)
+	RedriveDeadLettersFunc     func(ctx context.Context, messageIDs []string) error
+	CreateAPIKeyFunc           func(ctx context.Context, userName string, scopes []string) (model.IssuedAPIKey, error)
+	GetAPIKeysFunc             func(ctx context.Context) ([]model.APIKey, error)
+	RevokeAPIKeyFunc           func(ctx context.Context, apiKeyID string) error
+	RotateAPIKeyFunc           func(ctx context.Context, apiKeyID string) (model.IssuedAPIKey, error)
+	AuthenticateAPIKeyFunc     func(ctx context.Context, key string) (model.User, error)
+	GetUsageFunc               func(ctx context.Context, recipientUserName string) (model.Usage, error)
 }
 
-func (s *Service) SubmitMessage(ctx context.Context, recipientUserName, messageContent string) (string, error) {
-	return s.SubmitMessageFunc(ctx, recipientUserName, messageContent)
+func (s *Service) SubmitMessage(ctx context.Context, submission model.MessageSubmission) (string, error) {
+	return s.SubmitMessageFunc(ctx, submission)
 }
 
-func (s *Service) FetchNewMessages(ctx context.Context) ([]model.Message, error) {
-	return s.FetchNewMessagesFunc(ctx)
+func (s *Service) SubmitMessages(ctx context.Context, submissions []model.MessageSubmission) ([]string, error) {
+	return s.SubmitMessagesFunc(ctx, submissions)
+}
+
+func (s *Service) FetchNewMessages(ctx context.Context, recipientUserName string) ([]model.Message, error) {
+	return s.FetchNewMessagesFunc(ctx, recipientUserName)
+}
+
+func (s *Service) WaitForNewMessages(ctx context.Context, recipientUserName string, wait time.Duration) ([]model.Message, error) {
+	return s.WaitForNewMessagesFunc(ctx, recipientUserName, wait)
+}
+
+func (s *Service) SubscribeNewMessages(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+	return s.SubscribeNewMessagesFunc(ctx, recipientUserName)
+}
+
+func (s *Service) AcknowledgeMessages(ctx context.Context, messageIDs []string) error {
+	return s.AcknowledgeMessagesFunc(ctx, messageIDs)
+}
+
+func (s *Service) MarkMessagesUnfetched(ctx context.Context, messageIDs []string) error {
+	return s.MarkMessagesUnfetchedFunc(ctx, messageIDs)
+}
+
+func (s *Service) CancelScheduledMessage(ctx context.Context, messageID string) error {
+	return s.CancelScheduledMessageFunc(ctx, messageID)
 }
 
 func (s *Service) DeleteMessages(ctx context.Context, messageIDs []string) error {
 	return s.DeleteMessagesFunc(ctx, messageIDs)
 }
 
-func (s *Service) GetAllMessages(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error) {
-	return s.GetAllMessagesFunc(ctx, startCursor, endCursor)
+func (s *Service) DeleteExistingMessages(ctx context.Context, messageIDs []string) (model.DeleteResult, error) {
+	return s.DeleteExistingMessagesFunc(ctx, messageIDs)
+}
+
+func (s *Service) GetTrash(ctx context.Context, recipientUserName *string) ([]model.Message, error) {
+	return s.GetTrashFunc(ctx, recipientUserName)
+}
+
+func (s *Service) RestoreMessages(ctx context.Context, messageIDs []string) error {
+	return s.RestoreMessagesFunc(ctx, messageIDs)
+}
+
+func (s *Service) GetMessage(ctx context.Context, messageID string) (model.Message, error) {
+	return s.GetMessageFunc(ctx, messageID)
+}
+
+func (s *Service) GetAllMessages(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+	return s.GetAllMessagesFunc(ctx, filter, page)
+}
+
+func (s *Service) SearchMessages(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error) {
+	return s.SearchMessagesFunc(ctx, recipientUserName, query, limit, offset)
+}
+
+func (s *Service) GetSentMessages(ctx context.Context, senderUserName string) ([]model.Message, error) {
+	return s.GetSentMessagesFunc(ctx, senderUserName)
+}
+
+func (s *Service) GetReceivedMessages(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+	return s.GetReceivedMessagesFunc(ctx, recipientUserName, afterCursor, limit)
+}
+
+func (s *Service) GetDeadLetters(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+	return s.GetDeadLettersFunc(ctx, recipientUserName)
+}
+
+func (s *Service) RedriveDeadLetters(ctx context.Context, messageIDs []string) error {
+	return s.RedriveDeadLettersFunc(ctx, messageIDs)
+}
+
+func (s *Service) CreateAPIKey(ctx context.Context, userName string, scopes []string) (model.IssuedAPIKey, error) {
+	return s.CreateAPIKeyFunc(ctx, userName, scopes)
+}
+
+func (s *Service) GetAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	return s.GetAPIKeysFunc(ctx)
+}
+
+func (s *Service) RevokeAPIKey(ctx context.Context, apiKeyID string) error {
+	return s.RevokeAPIKeyFunc(ctx, apiKeyID)
+}
+
+func (s *Service) RotateAPIKey(ctx context.Context, apiKeyID string) (model.IssuedAPIKey, error) {
+	return s.RotateAPIKeyFunc(ctx, apiKeyID)
+}
+
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string) (model.User, error) {
+	return s.AuthenticateAPIKeyFunc(ctx, key)
+}
+
+func (s *Service) GetUsage(ctx context.Context, recipientUserName string) (model.Usage, error) {
+	return s.GetUsageFunc(ctx, recipientUserName)
 }
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+const (
+	ScopeMessagesSend   = "messages:send"
+	ScopeMessagesRead   = "messages:read"
+	ScopeMessagesDelete = "messages:delete"
+)
+
+// Scopes are all scopes an API key can be granted.
+var Scopes = []string{ScopeMessagesSend, ScopeMessagesRead, ScopeMessagesDelete}
+
+// APIKey lets a service call the API on behalf of a user without interactive
+// login. Only a hash of the key itself is stored.
+type APIKey struct {
+	ID        string     `json:"id"`
+	UserName  string     `json:"user_name"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IssuedAPIKey is an API key together with the key itself, which is only
+// available right after the key has been created or rotated.
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
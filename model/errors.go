@@ -1,5 +1,28 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrConflict      = errors.New("conflict")
+	ErrForbidden     = errors.New("forbidden")
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// MissingMessagesError tells which of the requested messages do not exist. It
+// matches ErrNotFound.
+type MissingMessagesError struct {
+	MessageIDs []string
+}
+
+func (e *MissingMessagesError) Error() string {
+	return fmt.Sprintf("messages %s: %v", strings.Join(e.MessageIDs, ", "), ErrNotFound)
+}
+
+func (e *MissingMessagesError) Unwrap() error {
+	return ErrNotFound
+}
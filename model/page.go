@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// PageCursor points between two messages of a list ordered by sent_at and ID.
+type PageCursor struct {
+	SentAt time.Time
+	ID     string
+	// Before selects the messages preceding the cursor instead of the ones
+	// following it.
+	Before bool
+}
+
+// PageRequest selects a page of at most Limit messages starting at Cursor, or
+// at the beginning of the list if Cursor is nil.
+type PageRequest struct {
+	Limit  int
+	Cursor *PageCursor
+}
+
+// MessagePage holds a page of messages and the cursors of the adjacent pages,
+// which are nil if there is no such page.
+type MessagePage struct {
+	Messages   []Message
+	NextCursor *PageCursor
+	PrevCursor *PageCursor
+}
+
+// SearchResult is a message found by a full-text search together with its
+// relevance and an excerpt of its content with the matches highlighted.
+type SearchResult struct {
+	Message
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchPage holds a page of search results and the offsets of the adjacent
+// pages, which are nil if there is no such page.
+type SearchPage struct {
+	Results    []SearchResult
+	NextOffset *int
+	PrevOffset *int
+}
@@ -0,0 +1,40 @@
+package model
+
+import "fmt"
+
+// Quota caps what a recipient can accumulate. A value of zero does not cap
+// anything.
+type Quota struct {
+	MaxUnfetchedMessages int   `json:"max_unfetched_messages"`
+	MaxBytes             int64 `json:"max_bytes"`
+}
+
+// Usage is what a recipient has accumulated together with the quota that
+// applies to the recipient. Bytes counts the content of all messages that are
+// neither deleted nor expired.
+type Usage struct {
+	UserName          string `json:"user_name"`
+	UnfetchedMessages int    `json:"unfetched_messages"`
+	Bytes             int64  `json:"bytes"`
+	Quota             Quota  `json:"quota"`
+}
+
+const (
+	QuotaUnfetchedMessages = "unfetched_messages"
+	QuotaBytes             = "bytes"
+)
+
+// QuotaExceededError tells which quota of a recipient a submission would
+// exceed. It matches ErrQuotaExceeded.
+type QuotaExceededError struct {
+	UserName string
+	Quota    string
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota of recipient %q: %v", e.Quota, e.UserName, ErrQuotaExceeded)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
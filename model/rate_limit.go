@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// RateLimit allows Limit requests per Period. All of them can be made at once,
+// after which they become available again evenly over the period.
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+// RateLimitResult tells whether a request is within its rate limit.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the duration until the next request is allowed. It is only
+	// set if the request was not allowed.
+	RetryAfter time.Duration
+	// Reset is the duration until all requests of the limit are available again.
+	Reset time.Duration
+}
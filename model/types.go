@@ -4,8 +4,72 @@ import "time"
 
 type Message struct {
 	ID                string     `json:"id"`
+	SenderUserName    string     `json:"sender_user_name"`
 	RecipientUserName string     `json:"recipient_user_name"`
 	Content           string     `json:"content"`
 	SentAt            time.Time  `json:"sent_at"`
+	DeliverAt         *time.Time `json:"deliver_at,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
 	FetchedAt         *time.Time `json:"fetched_at,omitempty"`
+	LeaseExpiresAt    *time.Time `json:"lease_expires_at,omitempty"`
+	AcknowledgedAt    *time.Time `json:"acknowledged_at,omitempty"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
+	ReceiveCount      int        `json:"receive_count"`
+	IdempotencyKey    string     `json:"-"`
+}
+
+// MessageSubmission holds what a client provides when submitting a message.
+type MessageSubmission struct {
+	SenderUserName    string
+	RecipientUserName string
+	Content           string
+	IdempotencyKey    string
+	// DeliverAt delays the delivery of the message until the given time.
+	DeliverAt *time.Time
+	// TTL is the duration after which the message expires. Zero means the
+	// message does not expire.
+	TTL time.Duration
+}
+
+type DeadLetter struct {
+	Message
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+// MessageEvent tells that a message was inserted. An event without a message
+// ID tells that events may have been missed and new messages of the recipient
+// should be looked for.
+type MessageEvent struct {
+	MessageID         string `json:"message_id"`
+	RecipientUserName string `json:"recipient_user_name"`
+}
+
+// DeleteResult tells which of the messages requested to be deleted were
+// deleted and which did not exist.
+type DeleteResult struct {
+	Deleted []string `json:"deleted"`
+	Missing []string `json:"missing"`
+}
+
+// User is the authenticated caller of the API.
+type User struct {
+	Name string
+	// Scopes are the operations the user is allowed to perform.
+	Scopes []string
+	// Admin tells that the user may manage API keys and the messages of all
+	// users.
+	Admin bool
+	// APIKeyID is the ID of the API key the user authenticated with, if any.
+	APIKeyID string
+}
+
+// HasScope reports whether the user is allowed to perform the operations of the
+// scope.
+func (u User) HasScope(scope string) bool {
+	for _, s := range u.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/jackc/pgx/v4"
+)
+
+func (r *Repository) InsertAPIKey(ctx context.Context, apiKey model.APIKey, keyHash []byte) error {
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO api_keys (
+			id,
+			user_name,
+			scopes,
+			key_hash,
+			created_at
+		) VALUES (
+			$1::text,
+			$2::text,
+			$3::text[],
+			$4::bytea,
+			$5::timestamptz
+		)
+	`,
+		apiKey.ID,
+		apiKey.UserName,
+		apiKey.Scopes,
+		keyHash,
+		apiKey.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("insert api key: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyByHash returns the API key with the hash unless it has been revoked.
+func (r *Repository) GetAPIKeyByHash(ctx context.Context, keyHash []byte) (model.APIKey, error) {
+	var apiKey model.APIKey
+	if err := r.pool.QueryRow(ctx, `
+		SELECT
+			id,
+			user_name,
+			scopes,
+			created_at,
+			rotated_at,
+			revoked_at
+		FROM api_keys
+		WHERE key_hash = $1::bytea
+		AND revoked_at IS NULL
+	`, keyHash).Scan(
+		&apiKey.ID,
+		&apiKey.UserName,
+		&apiKey.Scopes,
+		&apiKey.CreatedAt,
+		&apiKey.RotatedAt,
+		&apiKey.RevokedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.APIKey{}, fmt.Errorf("api key: %w", model.ErrNotFound)
+		}
+		return model.APIKey{}, fmt.Errorf("select api key: %w", err)
+	}
+
+	return apiKey, nil
+}
+
+func (r *Repository) GetAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			id,
+			user_name,
+			scopes,
+			created_at,
+			rotated_at,
+			revoked_at
+		FROM api_keys
+		ORDER BY created_at ASC, id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("select api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var apiKeys []model.APIKey
+	for rows.Next() {
+		var apiKey model.APIKey
+		if err := rows.Scan(
+			&apiKey.ID,
+			&apiKey.UserName,
+			&apiKey.Scopes,
+			&apiKey.CreatedAt,
+			&apiKey.RotatedAt,
+			&apiKey.RevokedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select api keys: %w", err)
+	}
+
+	return apiKeys, nil
+}
+
+// RevokeAPIKey makes the API key unusable. Revoked keys are kept, so that it
+// stays visible who had access.
+func (r *Repository) RevokeAPIKey(ctx context.Context, apiKeyID string, revokedAt time.Time) error {
+	if cmdTag, err := r.pool.Exec(ctx, `
+		UPDATE api_keys
+		SET revoked_at = $2::timestamptz
+		WHERE id = $1::text
+		AND revoked_at IS NULL
+	`, apiKeyID, revokedAt); err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	} else if cmdTag.RowsAffected() != 1 {
+		return fmt.Errorf("api key %q: %w", apiKeyID, model.ErrNotFound)
+	}
+
+	return nil
+}
+
+// RotateAPIKey replaces the hash of an API key that has not been revoked, which
+// invalidates the previous key right away.
+func (r *Repository) RotateAPIKey(ctx context.Context, apiKeyID string, keyHash []byte, rotatedAt time.Time) (model.APIKey, error) {
+	var apiKey model.APIKey
+	if err := r.pool.QueryRow(ctx, `
+		UPDATE api_keys
+		SET
+			key_hash = $2::bytea,
+			rotated_at = $3::timestamptz
+		WHERE id = $1::text
+		AND revoked_at IS NULL
+		RETURNING
+			id,
+			user_name,
+			scopes,
+			created_at,
+			rotated_at,
+			revoked_at
+	`, apiKeyID, keyHash, rotatedAt).Scan(
+		&apiKey.ID,
+		&apiKey.UserName,
+		&apiKey.Scopes,
+		&apiKey.CreatedAt,
+		&apiKey.RotatedAt,
+		&apiKey.RevokedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.APIKey{}, fmt.Errorf("api key %q: %w", apiKeyID, model.ErrNotFound)
+		}
+		return model.APIKey{}, fmt.Errorf("rotate api key: %w", err)
+	}
+
+	return apiKey, nil
+}
@@ -0,0 +1,125 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/RichterMaximilian/osttra-coding-assignment/postgres"
+	"github.com/RichterMaximilian/osttra-coding-assignment/testhelpers"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestRepository_GetAPIKeyByHash(t *testing.T) {
+	t.Run("should get an inserted API key by its hash", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		wantAPIKey := model.APIKey{
+			ID:        "api-key-id",
+			UserName:  "user",
+			Scopes:    []string{model.ScopeMessagesSend, model.ScopeMessagesRead},
+			CreatedAt: time.Now(),
+		}
+
+		if err := r.InsertAPIKey(ctx, wantAPIKey, []byte("hash")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotAPIKey, err := r.GetAPIKeyByHash(ctx, []byte("hash"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(wantAPIKey, gotAPIKey, cmpopts.EquateApproxTime(time.Millisecond)); diff != "" {
+			t.Errorf("api key mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should not get a revoked API key", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		apiKey := model.APIKey{ID: "api-key-id", UserName: "user", Scopes: []string{model.ScopeMessagesRead}, CreatedAt: time.Now()}
+		if err := r.InsertAPIKey(ctx, apiKey, []byte("hash")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := r.RevokeAPIKey(ctx, apiKey.ID, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, err := r.GetAPIKeyByHash(ctx, []byte("hash"))
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("got error %v, want %v", err, model.ErrNotFound)
+		}
+	})
+}
+
+func TestRepository_RevokeAPIKey(t *testing.T) {
+	t.Run("should return not found if the API key is already revoked", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		apiKey := model.APIKey{ID: "api-key-id", UserName: "user", Scopes: []string{model.ScopeMessagesRead}, CreatedAt: time.Now()}
+		if err := r.InsertAPIKey(ctx, apiKey, []byte("hash")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := r.RevokeAPIKey(ctx, apiKey.ID, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err := r.RevokeAPIKey(ctx, apiKey.ID, time.Now())
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("got error %v, want %v", err, model.ErrNotFound)
+		}
+	})
+}
+
+func TestRepository_RotateAPIKey(t *testing.T) {
+	t.Run("should replace the hash of the API key", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		apiKey := model.APIKey{ID: "api-key-id", UserName: "user", Scopes: []string{model.ScopeMessagesRead}, CreatedAt: time.Now()}
+		if err := r.InsertAPIKey(ctx, apiKey, []byte("old-hash")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		rotatedAt := time.Now()
+		if _, err := r.RotateAPIKey(ctx, apiKey.ID, []byte("new-hash"), rotatedAt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := r.GetAPIKeyByHash(ctx, []byte("old-hash")); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("got error %v, want %v", err, model.ErrNotFound)
+		}
+
+		gotAPIKey, err := r.GetAPIKeyByHash(ctx, []byte("new-hash"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantAPIKey := apiKey
+		wantAPIKey.RotatedAt = &rotatedAt
+		if diff := cmp.Diff(wantAPIKey, gotAPIKey, cmpopts.EquateApproxTime(time.Millisecond)); diff != "" {
+			t.Errorf("api key mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import "strconv"
+
+// queryArgs collects the arguments of a query that is built at runtime and
+// returns their placeholders, so that values never become part of the SQL.
+type queryArgs []interface{}
+
+func (a *queryArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// distinct returns the values without duplicates in the order of their first
+// occurrence, so that they can be compared with the number of affected rows.
+func distinct(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
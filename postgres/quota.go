@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+)
+
+// GetUsages counts what each of the recipients has accumulated and returns it
+// together with the quota of the recipient, in the order of the recipients.
+// Limits not overridden for a recipient in the quotas table are taken from
+// defaultQuota.
+func (r *Repository) GetUsages(ctx context.Context, recipientUserNames []string, defaultQuota model.Quota) ([]model.Usage, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			recipients.user_name,
+			COALESCE(usages.unfetched_messages, 0),
+			COALESCE(usages.bytes, 0),
+			COALESCE(quotas.max_unfetched_messages, $2::integer),
+			COALESCE(quotas.max_bytes, $3::bigint)
+		FROM unnest($1::text[]) WITH ORDINALITY AS recipients(user_name, position)
+		LEFT JOIN (
+			SELECT
+				user_name,
+				COUNT(*) FILTER (WHERE fetched_at IS NULL) AS unfetched_messages,
+				SUM(octet_length(content)) AS bytes
+			FROM messages
+			WHERE user_name = ANY($1::text[])
+			AND deleted_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+			GROUP BY user_name
+		) AS usages ON usages.user_name = recipients.user_name
+		LEFT JOIN quotas ON quotas.user_name = recipients.user_name
+		ORDER BY recipients.position
+	`, recipientUserNames, defaultQuota.MaxUnfetchedMessages, defaultQuota.MaxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("select usages: %w", err)
+	}
+	defer rows.Close()
+
+	usages := make([]model.Usage, 0, len(recipientUserNames))
+	for rows.Next() {
+		var usage model.Usage
+		if err := rows.Scan(
+			&usage.UserName,
+			&usage.UnfetchedMessages,
+			&usage.Bytes,
+			&usage.Quota.MaxUnfetchedMessages,
+			&usage.Quota.MaxBytes,
+		); err != nil {
+			return nil, fmt.Errorf("scan usage: %w", err)
+		}
+		usages = append(usages, usage)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select usages: %w", err)
+	}
+
+	return usages, nil
+}
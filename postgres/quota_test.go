@@ -0,0 +1,102 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/RichterMaximilian/osttra-coding-assignment/postgres"
+	"github.com/RichterMaximilian/osttra-coding-assignment/testhelpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRepository_GetUsages(t *testing.T) {
+	t.Run("should count unfetched messages and bytes of the recipient", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		expiredAt := now.Add(-time.Minute)
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient", Content: "unfetched", SentAt: now})
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id2", RecipientUserName: "recipient", Content: "fetched", SentAt: now, FetchedAt: &now})
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id3", RecipientUserName: "recipient", Content: "deleted", SentAt: now, DeletedAt: &now})
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id4", RecipientUserName: "recipient", Content: "expired", SentAt: now, ExpiresAt: &expiredAt})
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id5", RecipientUserName: "other-recipient", Content: "other", SentAt: now})
+
+		defaultQuota := model.Quota{MaxUnfetchedMessages: 10, MaxBytes: 1000}
+		gotUsages, err := r.GetUsages(ctx, []string{"recipient"}, defaultQuota)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantUsages := []model.Usage{
+			{
+				UserName:          "recipient",
+				UnfetchedMessages: 1,
+				Bytes:             int64(len("unfetched") + len("fetched")),
+				Quota:             defaultQuota,
+			},
+		}
+		if diff := cmp.Diff(wantUsages, gotUsages); diff != "" {
+			t.Errorf("usage mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should override the default quota with the quota of the recipient", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO quotas (user_name, max_unfetched_messages) VALUES ('recipient', 50)
+		`); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotUsages, err := r.GetUsages(ctx, []string{"recipient"}, model.Quota{MaxUnfetchedMessages: 10, MaxBytes: 1000})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantQuota := model.Quota{MaxUnfetchedMessages: 50, MaxBytes: 1000}
+		if diff := cmp.Diff(wantQuota, gotUsages[0].Quota); diff != "" {
+			t.Errorf("quota mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return the usages of all recipients in their order", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient-1", Content: "content-1", SentAt: now})
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id2", RecipientUserName: "recipient-2", Content: "content-2", SentAt: now})
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id3", RecipientUserName: "recipient-2", Content: "content-3", SentAt: now})
+
+		defaultQuota := model.Quota{MaxUnfetchedMessages: 10, MaxBytes: 1000}
+		gotUsages, err := r.GetUsages(ctx, []string{"recipient-2", "no-messages", "recipient-1"}, defaultQuota)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantUsages := []model.Usage{
+			{UserName: "recipient-2", UnfetchedMessages: 2, Bytes: int64(len("content-2") + len("content-3")), Quota: defaultQuota},
+			{UserName: "no-messages", Quota: defaultQuota},
+			{UserName: "recipient-1", UnfetchedMessages: 1, Bytes: int64(len("content-1")), Quota: defaultQuota},
+		}
+		if diff := cmp.Diff(wantUsages, gotUsages); diff != "" {
+			t.Errorf("usages mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// RateLimiter keeps the token buckets in the database, so that limits hold
+// across all instances of the service.
+type RateLimiter struct {
+	pool *pgxpool.Pool
+}
+
+func NewRateLimiter(pool *pgxpool.Pool) *RateLimiter {
+	return &RateLimiter{
+		pool: pool,
+	}
+}
+
+// Take takes a token from the bucket of the key. The bucket is locked while it
+// is updated and the time is taken from the database, so that concurrent
+// requests of different instances neither race nor depend on their clocks.
+func (l *RateLimiter) Take(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitResult, error) {
+	tx, err := l.pool.Begin(ctx)
+	if err != nil {
+		return model.RateLimitResult{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO rate_limits (
+			key,
+			tokens,
+			updated_at
+		) VALUES (
+			$1::text,
+			$2::double precision,
+			clock_timestamp()
+		)
+		ON CONFLICT (key) DO NOTHING
+	`, key, float64(limit.Limit)); err != nil {
+		return model.RateLimitResult{}, fmt.Errorf("insert rate limit: %w", err)
+	}
+
+	var bucket core.TokenBucket
+	var now time.Time
+	if err := tx.QueryRow(ctx, `
+		SELECT
+			tokens,
+			updated_at,
+			clock_timestamp()
+		FROM rate_limits
+		WHERE key = $1::text
+		FOR UPDATE
+	`, key).Scan(
+		&bucket.Tokens,
+		&bucket.UpdatedAt,
+		&now,
+	); err != nil {
+		return model.RateLimitResult{}, fmt.Errorf("select rate limit: %w", err)
+	}
+
+	bucket, result := bucket.Take(limit, now)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE rate_limits
+		SET
+			tokens = $2::double precision,
+			updated_at = $3::timestamptz,
+			full_at = $4::timestamptz
+		WHERE key = $1::text
+	`, key, bucket.Tokens, bucket.UpdatedAt, now.Add(result.Reset)); err != nil {
+		return model.RateLimitResult{}, fmt.Errorf("update rate limit: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.RateLimitResult{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// PruneRateLimits deletes up to limit buckets that have been refilled
+// completely, as they are equal to new ones, and returns the number of deleted
+// buckets.
+func (l *RateLimiter) PruneRateLimits(ctx context.Context, limit int) (int64, error) {
+	cmdTag, err := l.pool.Exec(ctx, `
+		DELETE FROM rate_limits
+		WHERE key IN (
+			SELECT key
+			FROM rate_limits
+			WHERE full_at <= clock_timestamp()
+			LIMIT $1::integer
+			FOR UPDATE SKIP LOCKED
+		)
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("delete rate limits: %w", err)
+	}
+
+	return cmdTag.RowsAffected(), nil
+}
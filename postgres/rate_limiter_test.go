@@ -0,0 +1,109 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/RichterMaximilian/osttra-coding-assignment/postgres"
+	"github.com/RichterMaximilian/osttra-coding-assignment/testhelpers"
+)
+
+func TestRateLimiter_Take(t *testing.T) {
+	t.Run("should share the bucket of a key between rate limiters", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		limit := model.RateLimit{Limit: 2, Period: time.Hour}
+
+		result, err := postgres.NewRateLimiter(pool.Pool).Take(ctx, "key", limit)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := result.Allowed, true; got != want {
+			t.Errorf("got allowed %t, want %t", got, want)
+		}
+		if got, want := result.Remaining, 1; got != want {
+			t.Errorf("got remaining %d, want %d", got, want)
+		}
+
+		result, err = postgres.NewRateLimiter(pool.Pool).Take(ctx, "key", limit)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := result.Allowed, true; got != want {
+			t.Errorf("got allowed %t, want %t", got, want)
+		}
+
+		result, err = postgres.NewRateLimiter(pool.Pool).Take(ctx, "key", limit)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := result.Allowed, false; got != want {
+			t.Errorf("got allowed %t, want %t", got, want)
+		}
+		if result.RetryAfter <= 0 {
+			t.Errorf("got retry after %v, want a positive duration", result.RetryAfter)
+		}
+	})
+
+	t.Run("should limit each key on its own", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRateLimiter(pool.Pool)
+		limit := model.RateLimit{Limit: 1, Period: time.Hour}
+
+		if _, err := r.Take(ctx, "key-1", limit); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		result, err := r.Take(ctx, "key-2", limit)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := result.Allowed, true; got != want {
+			t.Errorf("got allowed %t, want %t", got, want)
+		}
+	})
+}
+
+func TestRateLimiter_PruneRateLimits(t *testing.T) {
+	t.Run("should only delete full buckets", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRateLimiter(pool.Pool)
+
+		if _, err := r.Take(ctx, "full", model.RateLimit{Limit: 1000, Period: time.Millisecond}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := r.Take(ctx, "used", model.RateLimit{Limit: 1, Period: time.Hour}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		pruned, err := r.PruneRateLimits(ctx, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := pruned, int64(1); got != want {
+			t.Errorf("got pruned %d, want %d", got, want)
+		}
+
+		var key string
+		if err := pool.QueryRow(ctx, `
+			SELECT key FROM rate_limits
+		`).Scan(&key); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := key, "used"; got != want {
+			t.Errorf("got key %q, want %q", got, want)
+		}
+	})
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
 	"github.com/RichterMaximilian/osttra-coding-assignment/model"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -21,89 +23,458 @@ func NewRepository(pool *pgxpool.Pool) *Repository {
 	}
 }
 
-func (r *Repository) InsertMessage(ctx context.Context, message model.Message) error {
-	if _, err := r.pool.Exec(ctx, `
+func (r *Repository) InsertMessage(ctx context.Context, message model.Message, idempotencyWindow time.Duration) (string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if message.IdempotencyKey != "" {
+		// Idempotency keys are only unique within the idempotency window, so the
+		// key is released from older messages before it is used again.
+		if _, err := tx.Exec(ctx, `
+			UPDATE messages
+			SET idempotency_key = NULL
+			WHERE sender_user_name = $1::text
+			AND idempotency_key = $2::text
+			AND sent_at < NOW() - $3::interval
+		`, message.SenderUserName, message.IdempotencyKey, idempotencyWindow); err != nil {
+			return "", fmt.Errorf("release idempotency key: %w", err)
+		}
+	}
+
+	var messageID string
+	err = tx.QueryRow(ctx, `
 		INSERT INTO messages (
 			id,
 			user_name,
+			sender_user_name,
 			content,
-			sent_at
+			sent_at,
+			deliver_at,
+			expires_at,
+			idempotency_key
 		) VALUES (
 			$1::text,
 			$2::text,
 			$3::text,
-			$4::timestamptz
+			$4::text,
+			$5::timestamptz,
+			$6::timestamptz,
+			$7::timestamptz,
+			NULLIF($8::text, '')
 		)
+		ON CONFLICT (sender_user_name, idempotency_key) WHERE idempotency_key IS NOT NULL DO NOTHING
+		RETURNING id
 	`,
 		message.ID,
 		message.RecipientUserName,
+		message.SenderUserName,
 		message.Content,
 		message.SentAt,
+		message.DeliverAt,
+		message.ExpiresAt,
+		message.IdempotencyKey,
+	).Scan(&messageID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The message has already been submitted with the same idempotency key.
+		if err := tx.QueryRow(ctx, `
+			SELECT id
+			FROM messages
+			WHERE sender_user_name = $1::text
+			AND idempotency_key = $2::text
+		`, message.SenderUserName, message.IdempotencyKey).Scan(&messageID); err != nil {
+			return "", fmt.Errorf("select message id: %w", err)
+		}
+	} else if err != nil {
+		return "", fmt.Errorf("insert message: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// GetIdempotentMessageID returns the ID of the message the sender submitted
+// with the idempotency key within the idempotency window, or model.ErrNotFound
+// if there is none.
+func (r *Repository) GetIdempotentMessageID(ctx context.Context, senderUserName, idempotencyKey string, idempotencyWindow time.Duration) (string, error) {
+	var messageID string
+	if err := r.pool.QueryRow(ctx, `
+		SELECT id
+		FROM messages
+		WHERE sender_user_name = $1::text
+		AND idempotency_key = $2::text
+		AND sent_at >= NOW() - $3::interval
+	`, senderUserName, idempotencyKey, idempotencyWindow).Scan(&messageID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("idempotency key %q: %w", idempotencyKey, model.ErrNotFound)
+		}
+		return "", fmt.Errorf("select message id: %w", err)
+	}
+
+	return messageID, nil
+}
+
+func (r *Repository) InsertMessages(ctx context.Context, messages []model.Message) error {
+	rows := make([][]interface{}, 0, len(messages))
+	for _, message := range messages {
+		rows = append(rows, []interface{}{
+			message.ID,
+			message.RecipientUserName,
+			message.SenderUserName,
+			message.Content,
+			message.SentAt,
+			message.DeliverAt,
+			message.ExpiresAt,
+		})
+	}
+
+	// COPY runs as a single statement, so either all or none of the messages
+	// are inserted.
+	if _, err := r.pool.CopyFrom(
+		ctx,
+		pgx.Identifier{"messages"},
+		[]string{"id", "user_name", "sender_user_name", "content", "sent_at", "deliver_at", "expires_at"},
+		pgx.CopyFromRows(rows),
 	); err != nil {
-		return fmt.Errorf("insert message: %w", err)
+		return fmt.Errorf("copy messages: %w", err)
 	}
 
 	return nil
 }
 
-func (r *Repository) GetNewMessages(ctx context.Context) ([]model.Message, error) {
-	rows, err := r.pool.Query(ctx, `
+func (r *Repository) GetNewMessages(ctx context.Context, recipientUserName string, visibilityTimeout time.Duration, maxReceiveCount int) ([]model.Message, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if maxReceiveCount > 0 {
+		if err := moveToDeadLetters(ctx, tx, recipientUserName, maxReceiveCount); err != nil {
+			return nil, fmt.Errorf("move to dead letters: %w", err)
+		}
+	}
+
+	// Selecting and leasing the messages happens in a single statement, so that
+	// concurrent callers claim disjoint sets of messages. Rows locked by another
+	// caller are skipped instead of being returned twice.
+	rows, err := tx.Query(ctx, `
+		WITH claimed AS (
+			SELECT id
+			FROM messages
+			WHERE user_name = $1::text
+			AND acknowledged_at IS NULL
+			AND deleted_at IS NULL
+			AND (lease_expires_at IS NULL OR lease_expires_at <= NOW())
+			AND (deliver_at IS NULL OR deliver_at <= NOW())
+			AND (expires_at IS NULL OR expires_at > NOW())
+			ORDER BY sent_at ASC
+			FOR UPDATE SKIP LOCKED
+		), fetched AS (
+			UPDATE messages
+			SET
+				fetched_at = NOW(),
+				lease_expires_at = NOW() + $2::interval,
+				receive_count = receive_count + 1
+			FROM claimed
+			WHERE messages.id = claimed.id
+			RETURNING
+				messages.id,
+				messages.user_name,
+				messages.sender_user_name,
+				messages.content,
+				messages.sent_at,
+				messages.deliver_at,
+				messages.expires_at,
+				messages.fetched_at,
+				messages.lease_expires_at,
+				messages.receive_count
+		)
 		SELECT
 			id,
 			user_name,
+			sender_user_name,
 			content,
-			sent_at
-		FROM messages
-		WHERE fetched_at IS NULL
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at,
+			lease_expires_at,
+			receive_count
+		FROM fetched
 		ORDER BY sent_at ASC
-	`)
+	`, recipientUserName, visibilityTimeout)
 	if err != nil {
-		return nil, fmt.Errorf("select messages: %w", err)
+		return nil, fmt.Errorf("claim messages: %w", err)
 	}
 	defer rows.Close()
 
 	var messages []model.Message
-	var messageIDs []string
 	for rows.Next() {
 		var message model.Message
 		if err := rows.Scan(
 			&message.ID,
 			&message.RecipientUserName,
+			&message.SenderUserName,
 			&message.Content,
 			&message.SentAt,
+			&message.DeliverAt,
+			&message.ExpiresAt,
+			&message.FetchedAt,
+			&message.LeaseExpiresAt,
+			&message.ReceiveCount,
 		); err != nil {
 			return nil, fmt.Errorf("scan message: %w", err)
 		}
 
 		messages = append(messages, message)
-		messageIDs = append(messageIDs, message.ID)
 	}
 
-	if _, err := r.pool.Exec(ctx, `
-		UPDATE messages
-		SET fetched_at = NOW()
-		WHERE id = ANY($1::text[])
-	`, messageIDs); err != nil {
-		return nil, fmt.Errorf("update fetched_at: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claim messages: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
 	}
 
 	return messages, nil
 }
 
-func (r *Repository) DeleteMessages(ctx context.Context, messageIDs []string) error {
+// moveToDeadLetters moves the messages of the recipient that have been received
+// maxReceiveCount times without being acknowledged to the dead letters.
+func moveToDeadLetters(ctx context.Context, tx pgx.Tx, recipientUserName string, maxReceiveCount int) error {
+	if _, err := tx.Exec(ctx, `
+		WITH dead AS (
+			DELETE FROM messages
+			WHERE id IN (
+				SELECT id
+				FROM messages
+				WHERE user_name = $1::text
+				AND acknowledged_at IS NULL
+				AND deleted_at IS NULL
+				AND lease_expires_at <= NOW()
+				AND receive_count >= $2::integer
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING
+				id,
+				user_name,
+				sender_user_name,
+				content,
+				sent_at,
+				deliver_at,
+				expires_at,
+				fetched_at,
+				receive_count
+		)
+		INSERT INTO dead_letters (
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at,
+			receive_count,
+			dead_lettered_at
+		)
+		SELECT
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at,
+			receive_count,
+			NOW()
+		FROM dead
+	`, recipientUserName, maxReceiveCount); err != nil {
+		return fmt.Errorf("move messages: %w", err)
+	}
+
+	return nil
+}
+
+// AcknowledgeMessages marks fetched messages as processed. If recipientUserName
+// is set and any message belongs to another recipient, none is acknowledged and
+// model.ErrForbidden is returned.
+func (r *Repository) AcknowledgeMessages(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+	messageIDs = distinct(messageIDs)
+
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	if cmdTag, err := tx.Exec(ctx, `
-		DELETE FROM messages
-		WHERE id = ANY($1::text[])
-	`, messageIDs); err != nil {
-		return fmt.Errorf("delete messages: %w", err)
-	} else if cmdTag.RowsAffected() != int64(len(messageIDs)) {
-		return fmt.Errorf("delete messages: %w", model.ErrNotFound)
+	// The update runs even if messages are missing, in which case the
+	// transaction is rolled back.
+	var found, forbidden int
+	if err := tx.QueryRow(ctx, `
+		WITH targets AS (
+			SELECT
+				id,
+				($2::text IS NULL OR user_name = $2::text) AS owned
+			FROM messages
+			WHERE id = ANY($1::text[])
+			AND fetched_at IS NOT NULL
+			AND deleted_at IS NULL
+			FOR UPDATE
+		), acknowledged AS (
+			UPDATE messages
+			SET
+				acknowledged_at = COALESCE(acknowledged_at, NOW()),
+				lease_expires_at = NULL
+			WHERE id IN (SELECT id FROM targets)
+			AND NOT EXISTS (SELECT 1 FROM targets WHERE NOT owned)
+		)
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE NOT owned)
+		FROM targets
+	`, messageIDs, recipientUserName).Scan(&found, &forbidden); err != nil {
+		return fmt.Errorf("acknowledge messages: %w", err)
+	}
+
+	if forbidden > 0 {
+		return fmt.Errorf("acknowledge messages: %w", model.ErrForbidden)
+	}
+
+	if found != len(messageIDs) {
+		return fmt.Errorf("acknowledge messages: %w", model.ErrNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// MarkMessagesUnfetched makes fetched messages new again, so that they are
+// returned by GetNewMessages as if they had never been fetched. Their receive
+// count starts over, so that they are not moved to the dead letters right away.
+// If recipientUserName is set and any message belongs to another recipient,
+// none is changed and model.ErrForbidden is returned.
+func (r *Repository) MarkMessagesUnfetched(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+	messageIDs = distinct(messageIDs)
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var found, forbidden int
+	if err := tx.QueryRow(ctx, `
+		WITH targets AS (
+			SELECT
+				id,
+				($2::text IS NULL OR user_name = $2::text) AS owned
+			FROM messages
+			WHERE id = ANY($1::text[])
+			AND deleted_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+			FOR UPDATE
+		), unfetched AS (
+			UPDATE messages
+			SET
+				fetched_at = NULL,
+				lease_expires_at = NULL,
+				acknowledged_at = NULL,
+				receive_count = 0
+			WHERE id IN (SELECT id FROM targets)
+			AND NOT EXISTS (SELECT 1 FROM targets WHERE NOT owned)
+		)
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE NOT owned)
+		FROM targets
+	`, messageIDs, recipientUserName).Scan(&found, &forbidden); err != nil {
+		return fmt.Errorf("mark messages unfetched: %w", err)
+	}
+
+	if forbidden > 0 {
+		return fmt.Errorf("mark messages unfetched: %w", model.ErrForbidden)
+	}
+
+	if found != len(messageIDs) {
+		return fmt.Errorf("mark messages unfetched: %w", model.ErrNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CancelScheduledMessage deletes a message whose delivery time has not been
+// reached yet. If senderUserName is set and the message was sent by another
+// user, it is not deleted and model.ErrForbidden is returned.
+func (r *Repository) CancelScheduledMessage(ctx context.Context, senderUserName *string, messageID string) error {
+	var scheduled, owned bool
+	if err := r.pool.QueryRow(ctx, `
+		WITH target AS (
+			SELECT
+				id,
+				COALESCE(deliver_at > NOW(), false) AS scheduled,
+				($2::text IS NULL OR sender_user_name = $2::text) AS owned
+			FROM messages
+			WHERE id = $1::text
+			AND deleted_at IS NULL
+			FOR UPDATE
+		), deleted AS (
+			DELETE FROM messages
+			WHERE id IN (SELECT id FROM target WHERE scheduled AND owned)
+		)
+		SELECT scheduled, owned
+		FROM target
+	`, messageID, senderUserName).Scan(&scheduled, &owned); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("message %q: %w", messageID, model.ErrNotFound)
+		}
+		return fmt.Errorf("delete message: %w", err)
+	}
+
+	if !owned {
+		return fmt.Errorf("message %q: %w", messageID, model.ErrForbidden)
+	}
+
+	if !scheduled {
+		return fmt.Errorf("message %q already delivered: %w", messageID, model.ErrConflict)
+	}
+
+	return nil
+}
+
+// DeleteMessages moves all given messages to the trash. If any of them does not
+// exist, none is moved and a *model.MissingMessagesError is returned. If
+// recipientUserName is set and any message belongs to another recipient, none is
+// moved and model.ErrForbidden is returned.
+func (r *Repository) DeleteMessages(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := deleteMessages(ctx, tx, recipientUserName, messageIDs)
+	if err != nil {
+		return err
+	}
+
+	if len(result.Missing) > 0 {
+		return fmt.Errorf("delete messages: %w", &model.MissingMessagesError{MessageIDs: result.Missing})
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -113,36 +484,617 @@ func (r *Repository) DeleteMessages(ctx context.Context, messageIDs []string) er
 	return nil
 }
 
-func (r *Repository) GetAllMessages(ctx context.Context, startCursor, endCursor *string) ([]model.Message, error) {
-	var startAt, endAt *time.Time
-	if startCursor != nil {
-		cursor, err := r.getSentAt(ctx, *startCursor)
+// DeleteExistingMessages moves those of the given messages that exist to the
+// trash and reports which were deleted and which are missing. If
+// recipientUserName is set and any message belongs to another recipient, none is
+// moved and model.ErrForbidden is returned.
+func (r *Repository) DeleteExistingMessages(ctx context.Context, recipientUserName *string, messageIDs []string) (model.DeleteResult, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return model.DeleteResult{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := deleteMessages(ctx, tx, recipientUserName, messageIDs)
+	if err != nil {
+		return model.DeleteResult{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.DeleteResult{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// deleteMessages marks the messages as deleted. Messages already in the trash
+// count as missing. The ownership check is part of the statement, so that no
+// message can change its recipient between the check and the deletion.
+func deleteMessages(ctx context.Context, tx pgx.Tx, recipientUserName *string, messageIDs []string) (model.DeleteResult, error) {
+	rows, err := tx.Query(ctx, `
+		WITH targets AS (
+			SELECT
+				id,
+				($2::text IS NULL OR user_name = $2::text) AS owned
+			FROM messages
+			WHERE id = ANY($1::text[])
+			AND deleted_at IS NULL
+			FOR UPDATE
+		), deleted AS (
+			UPDATE messages
+			SET deleted_at = NOW()
+			WHERE id IN (SELECT id FROM targets)
+			AND NOT EXISTS (SELECT 1 FROM targets WHERE NOT owned)
+		)
+		SELECT
+			id,
+			owned
+		FROM targets
+	`, messageIDs, recipientUserName)
+	if err != nil {
+		return model.DeleteResult{}, fmt.Errorf("delete messages: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := make(map[string]bool, len(messageIDs))
+	forbidden := false
+	for rows.Next() {
+		var messageID string
+		var owned bool
+		if err := rows.Scan(&messageID, &owned); err != nil {
+			return model.DeleteResult{}, fmt.Errorf("scan message id: %w", err)
+		}
+		deleted[messageID] = true
+		forbidden = forbidden || !owned
+	}
+	if err := rows.Err(); err != nil {
+		return model.DeleteResult{}, fmt.Errorf("delete messages: %w", err)
+	}
+
+	if forbidden {
+		return model.DeleteResult{}, fmt.Errorf("delete messages: %w", model.ErrForbidden)
+	}
+
+	result := model.DeleteResult{Deleted: []string{}, Missing: []string{}}
+	seen := make(map[string]bool, len(messageIDs))
+	for _, messageID := range messageIDs {
+		if seen[messageID] {
+			continue
+		}
+		seen[messageID] = true
+
+		if deleted[messageID] {
+			result.Deleted = append(result.Deleted, messageID)
+		} else {
+			result.Missing = append(result.Missing, messageID)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *Repository) GetMessage(ctx context.Context, messageID string) (model.Message, error) {
+	var message model.Message
+	if err := r.pool.QueryRow(ctx, `
+		SELECT
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at,
+			lease_expires_at,
+			acknowledged_at,
+			receive_count
+		FROM messages
+		WHERE id = $1::text
+		AND deleted_at IS NULL
+		AND (expires_at IS NULL OR expires_at > NOW())
+	`, messageID).Scan(
+		&message.ID,
+		&message.RecipientUserName,
+		&message.SenderUserName,
+		&message.Content,
+		&message.SentAt,
+		&message.DeliverAt,
+		&message.ExpiresAt,
+		&message.FetchedAt,
+		&message.LeaseExpiresAt,
+		&message.AcknowledgedAt,
+		&message.ReceiveCount,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Message{}, fmt.Errorf("message %q: %w", messageID, model.ErrNotFound)
+		}
+		return model.Message{}, fmt.Errorf("select message: %w", err)
+	}
+
+	return message, nil
+}
+
+// GetAllMessages returns a page of the messages matching the filter. Messages
+// are ordered by sent_at and ID, which keeps the order stable for messages sent
+// at the same time.
+func (r *Repository) GetAllMessages(ctx context.Context, filter core.MessageFilter, page model.PageRequest) (model.MessagePage, error) {
+	var args queryArgs
+	conditions := []string{
+		"deleted_at IS NULL",
+		"(deliver_at IS NULL OR deliver_at <= NOW())",
+		"(expires_at IS NULL OR expires_at > NOW())",
+	}
+
+	if filter.StartCursor != nil {
+		startAt, err := r.getSentAt(ctx, *filter.StartCursor)
 		if err != nil {
-			return nil, fmt.Errorf("get start at: %w", err)
+			return model.MessagePage{}, fmt.Errorf("get start at: %w", err)
 		}
-		startAt = &cursor
+		conditions = append(conditions, fmt.Sprintf("(sent_at, id) >= (%s::timestamptz, %s::text)", args.add(startAt), args.add(*filter.StartCursor)))
 	}
 
-	if endCursor != nil {
-		cursor, err := r.getSentAt(ctx, *endCursor)
+	if filter.EndCursor != nil {
+		endAt, err := r.getSentAt(ctx, *filter.EndCursor)
 		if err != nil {
-			return nil, fmt.Errorf("get end at: %w", err)
+			return model.MessagePage{}, fmt.Errorf("get end at: %w", err)
+		}
+		conditions = append(conditions, fmt.Sprintf("(sent_at, id) <= (%s::timestamptz, %s::text)", args.add(endAt), args.add(*filter.EndCursor)))
+	}
+
+	if filter.RecipientUserName != nil {
+		conditions = append(conditions, fmt.Sprintf("user_name = %s::text", args.add(*filter.RecipientUserName)))
+	}
+
+	if filter.Fetched != nil {
+		if *filter.Fetched {
+			conditions = append(conditions, "fetched_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "fetched_at IS NULL")
+		}
+	}
+
+	if filter.SentAfter != nil {
+		conditions = append(conditions, fmt.Sprintf("sent_at > %s::timestamptz", args.add(*filter.SentAfter)))
+	}
+
+	if filter.SentBefore != nil {
+		conditions = append(conditions, fmt.Sprintf("sent_at < %s::timestamptz", args.add(*filter.SentBefore)))
+	}
+
+	if filter.ContentContains != nil {
+		// strpos does not treat any characters of the text as wildcards,
+		// unlike LIKE.
+		conditions = append(conditions, fmt.Sprintf("strpos(lower(content), lower(%s::text)) > 0", args.add(*filter.ContentContains)))
+	}
+
+	// Pages before the cursor are selected in descending order, so that the
+	// limit applies to the messages closest to the cursor.
+	before := page.Cursor != nil && page.Cursor.Before
+	comparison, order := ">", "ASC"
+	if before {
+		comparison, order = "<", "DESC"
+	}
+
+	if page.Cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(sent_at, id) %s (%s::timestamptz, %s::text)", comparison, args.add(page.Cursor.SentAt), args.add(page.Cursor.ID)))
+	}
+
+	// One more message than requested is selected to find out whether there
+	// is another page.
+	limit := args.add(page.Limit + 1)
+
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at,
+			lease_expires_at,
+			acknowledged_at,
+			receive_count
+		FROM messages
+		WHERE %s
+		ORDER BY sent_at %s, id %s
+		LIMIT %s::integer
+	`, strings.Join(conditions, " AND "), order, order, limit), args...)
+	if err != nil {
+		return model.MessagePage{}, fmt.Errorf("select messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []model.Message
+	for rows.Next() {
+		var message model.Message
+		if err := rows.Scan(
+			&message.ID,
+			&message.RecipientUserName,
+			&message.SenderUserName,
+			&message.Content,
+			&message.SentAt,
+			&message.DeliverAt,
+			&message.ExpiresAt,
+			&message.FetchedAt,
+			&message.LeaseExpiresAt,
+			&message.AcknowledgedAt,
+			&message.ReceiveCount,
+		); err != nil {
+			return model.MessagePage{}, fmt.Errorf("scan message: %w", err)
 		}
-		endAt = &cursor
+
+		messages = append(messages, message)
+	}
+
+	hasMore := len(messages) > page.Limit
+	if hasMore {
+		messages = messages[:page.Limit]
+	}
+
+	if before {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	result := model.MessagePage{Messages: messages}
+	if len(messages) == 0 {
+		return result, nil
+	}
+
+	// Coming from a cursor means there are messages on the other side of it.
+	first, last := messages[0], messages[len(messages)-1]
+	if (!before && hasMore) || before {
+		result.NextCursor = &model.PageCursor{SentAt: last.SentAt, ID: last.ID}
+	}
+	if (before && hasMore) || (!before && page.Cursor != nil) {
+		result.PrevCursor = &model.PageCursor{SentAt: first.SentAt, ID: first.ID, Before: true}
 	}
 
+	return result, nil
+}
+
+// SearchMessages returns a page of the messages whose content matches the
+// query, written in the syntax of web search engines, ordered by relevance. If
+// recipientUserName is set, only messages of that recipient are searched.
+// Ranked results cannot be paged by a key, so pages are selected by offset. The
+// snippets are HTML, in which the matches are wrapped in <mark> tags.
+func (r *Repository) SearchMessages(ctx context.Context, recipientUserName *string, query string, limit, offset int) (model.SearchPage, error) {
 	rows, err := r.pool.Query(ctx, `
 		SELECT
 			id,
 			user_name,
+			sender_user_name,
 			content,
 			sent_at,
-			fetched_at
+			deliver_at,
+			expires_at,
+			fetched_at,
+			lease_expires_at,
+			acknowledged_at,
+			receive_count,
+			ts_rank(content_tsv, query) AS rank,
+			ts_headline('english', html_content, query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=3')
+		FROM
+			messages,
+			websearch_to_tsquery('english', $1::text) AS query,
+			-- The content is escaped before it is highlighted, so that only the
+			-- <mark> tags of the snippet are HTML.
+			replace(replace(replace(replace(replace(content,
+				'&', '&amp;'),
+				'<', '&lt;'),
+				'>', '&gt;'),
+				'"', '&quot;'),
+				'''', '&#39;') AS html_content
+		WHERE content_tsv @@ query
+		AND ($4::text IS NULL OR user_name = $4::text)
+		AND deleted_at IS NULL
+		AND (deliver_at IS NULL OR deliver_at <= NOW())
+		AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY rank DESC, sent_at DESC, id ASC
+		LIMIT $2::integer
+		OFFSET $3::integer
+	`, query, limit+1, offset, recipientUserName)
+	if err != nil {
+		return model.SearchPage{}, fmt.Errorf("search messages: %w", err)
+	}
+	defer rows.Close()
+
+	var results []model.SearchResult
+	for rows.Next() {
+		var result model.SearchResult
+		if err := rows.Scan(
+			&result.ID,
+			&result.RecipientUserName,
+			&result.SenderUserName,
+			&result.Content,
+			&result.SentAt,
+			&result.DeliverAt,
+			&result.ExpiresAt,
+			&result.FetchedAt,
+			&result.LeaseExpiresAt,
+			&result.AcknowledgedAt,
+			&result.ReceiveCount,
+			&result.Rank,
+			&result.Snippet,
+		); err != nil {
+			return model.SearchPage{}, fmt.Errorf("scan search result: %w", err)
+		}
+
+		results = append(results, result)
+	}
+
+	page := model.SearchPage{Results: results}
+	if len(results) > limit {
+		page.Results = results[:limit]
+		nextOffset := offset + limit
+		page.NextOffset = &nextOffset
+	}
+	if offset > 0 {
+		prevOffset := offset - limit
+		if prevOffset < 0 {
+			prevOffset = 0
+		}
+		page.PrevOffset = &prevOffset
+	}
+
+	return page, nil
+}
+
+func (r *Repository) GetSentMessages(ctx context.Context, senderUserName string) ([]model.Message, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at,
+			lease_expires_at,
+			acknowledged_at,
+			receive_count
 		FROM messages
-		WHERE ($1::timestamptz IS NULL OR sent_at >= $1::timestamptz)
-		AND ($2::timestamptz IS NULL OR sent_at <= $2::timestamptz)
+		WHERE sender_user_name = $1::text
+		AND deleted_at IS NULL
 		ORDER BY sent_at ASC
-	`, startAt, endAt)
+	`, senderUserName)
+	if err != nil {
+		return nil, fmt.Errorf("select messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []model.Message
+	for rows.Next() {
+		var message model.Message
+		if err := rows.Scan(
+			&message.ID,
+			&message.RecipientUserName,
+			&message.SenderUserName,
+			&message.Content,
+			&message.SentAt,
+			&message.DeliverAt,
+			&message.ExpiresAt,
+			&message.FetchedAt,
+			&message.LeaseExpiresAt,
+			&message.AcknowledgedAt,
+			&message.ReceiveCount,
+		); err != nil {
+			return nil, fmt.Errorf("scan message: %w", err)
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// GetReceivedMessages returns up to limit delivered messages of the recipient in
+// the order in which they became visible, starting after the message
+// afterCursor.
+// Unlike GetNewMessages it does not lease the messages. Scheduled messages are
+// ordered by their delivery time, so a cursor taken before they became due
+// does not skip them.
+func (r *Repository) GetReceivedMessages(ctx context.Context, recipientUserName string, afterCursor *string, limit int) ([]model.Message, error) {
+	var afterAt *time.Time
+	if afterCursor != nil {
+		var cursor time.Time
+		if err := r.pool.QueryRow(ctx, `
+			SELECT GREATEST(sent_at, deliver_at)
+			FROM messages
+			WHERE id = $1::text
+		`, *afterCursor).Scan(&cursor); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, fmt.Errorf("message %q: %w", *afterCursor, model.ErrNotFound)
+			}
+			return nil, fmt.Errorf("select visible at: %w", err)
+		}
+		afterAt = &cursor
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at,
+			lease_expires_at,
+			acknowledged_at,
+			receive_count
+		FROM messages
+		WHERE user_name = $1::text
+		AND deleted_at IS NULL
+		AND (deliver_at IS NULL OR deliver_at <= NOW())
+		AND (expires_at IS NULL OR expires_at > NOW())
+		AND ($2::timestamptz IS NULL OR (GREATEST(sent_at, deliver_at), id) > ($2::timestamptz, $3::text))
+		ORDER BY GREATEST(sent_at, deliver_at) ASC, id ASC
+		LIMIT $4::integer
+	`, recipientUserName, afterAt, afterCursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("select messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []model.Message
+	for rows.Next() {
+		var message model.Message
+		if err := rows.Scan(
+			&message.ID,
+			&message.RecipientUserName,
+			&message.SenderUserName,
+			&message.Content,
+			&message.SentAt,
+			&message.DeliverAt,
+			&message.ExpiresAt,
+			&message.FetchedAt,
+			&message.LeaseExpiresAt,
+			&message.AcknowledgedAt,
+			&message.ReceiveCount,
+		); err != nil {
+			return nil, fmt.Errorf("scan message: %w", err)
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+func (r *Repository) GetDeadLetters(ctx context.Context, recipientUserName *string) ([]model.DeadLetter, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at,
+			receive_count,
+			dead_lettered_at
+		FROM dead_letters
+		WHERE ($1::text IS NULL OR user_name = $1::text)
+		AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY dead_lettered_at ASC, sent_at ASC
+	`, recipientUserName)
+	if err != nil {
+		return nil, fmt.Errorf("select dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var deadLetters []model.DeadLetter
+	for rows.Next() {
+		var deadLetter model.DeadLetter
+		if err := rows.Scan(
+			&deadLetter.ID,
+			&deadLetter.RecipientUserName,
+			&deadLetter.SenderUserName,
+			&deadLetter.Content,
+			&deadLetter.SentAt,
+			&deadLetter.DeliverAt,
+			&deadLetter.ExpiresAt,
+			&deadLetter.FetchedAt,
+			&deadLetter.ReceiveCount,
+			&deadLetter.DeadLetteredAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan dead letter: %w", err)
+		}
+
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	return deadLetters, nil
+}
+
+func (r *Repository) RedriveDeadLetters(ctx context.Context, messageIDs []string) error {
+	messageIDs = distinct(messageIDs)
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Redriven messages start over with a fresh receive count, so that they are
+	// not moved back to the dead letters on the next fetch.
+	if cmdTag, err := tx.Exec(ctx, `
+		WITH redriven AS (
+			DELETE FROM dead_letters
+			WHERE id = ANY($1::text[])
+			RETURNING
+				id,
+				user_name,
+				sender_user_name,
+				content,
+				sent_at,
+				deliver_at,
+				expires_at,
+				fetched_at
+		)
+		INSERT INTO messages (
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at
+		)
+		SELECT
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at
+		FROM redriven
+	`, messageIDs); err != nil {
+		return fmt.Errorf("redrive dead letters: %w", err)
+	} else if cmdTag.RowsAffected() != int64(len(messageIDs)) {
+		return fmt.Errorf("redrive dead letters: %w", model.ErrNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetTrash returns the deleted messages that have not been purged yet, the most
+// recently deleted first.
+func (r *Repository) GetTrash(ctx context.Context, recipientUserName *string) ([]model.Message, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at,
+			lease_expires_at,
+			acknowledged_at,
+			deleted_at,
+			receive_count
+		FROM messages
+		WHERE deleted_at IS NOT NULL
+		AND ($1::text IS NULL OR user_name = $1::text)
+		AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY deleted_at DESC, id ASC
+	`, recipientUserName)
 	if err != nil {
 		return nil, fmt.Errorf("select messages: %w", err)
 	}
@@ -154,9 +1106,16 @@ func (r *Repository) GetAllMessages(ctx context.Context, startCursor, endCursor
 		if err := rows.Scan(
 			&message.ID,
 			&message.RecipientUserName,
+			&message.SenderUserName,
 			&message.Content,
 			&message.SentAt,
+			&message.DeliverAt,
+			&message.ExpiresAt,
 			&message.FetchedAt,
+			&message.LeaseExpiresAt,
+			&message.AcknowledgedAt,
+			&message.DeletedAt,
+			&message.ReceiveCount,
 		); err != nil {
 			return nil, fmt.Errorf("scan message: %w", err)
 		}
@@ -164,9 +1123,109 @@ func (r *Repository) GetAllMessages(ctx context.Context, startCursor, endCursor
 		messages = append(messages, message)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select messages: %w", err)
+	}
+
 	return messages, nil
 }
 
+// RestoreMessages moves messages back from the trash. If any of them is not in
+// the trash, none is restored and model.ErrNotFound is returned. If
+// recipientUserName is set and any message belongs to another recipient, none is
+// restored and model.ErrForbidden is returned.
+func (r *Repository) RestoreMessages(ctx context.Context, recipientUserName *string, messageIDs []string) error {
+	messageIDs = distinct(messageIDs)
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var found, forbidden int
+	if err := tx.QueryRow(ctx, `
+		WITH targets AS (
+			SELECT
+				id,
+				($2::text IS NULL OR user_name = $2::text) AS owned
+			FROM messages
+			WHERE id = ANY($1::text[])
+			AND deleted_at IS NOT NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+			FOR UPDATE
+		), restored AS (
+			UPDATE messages
+			SET deleted_at = NULL
+			WHERE id IN (SELECT id FROM targets)
+			AND NOT EXISTS (SELECT 1 FROM targets WHERE NOT owned)
+		)
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE NOT owned)
+		FROM targets
+	`, messageIDs, recipientUserName).Scan(&found, &forbidden); err != nil {
+		return fmt.Errorf("restore messages: %w", err)
+	}
+
+	if forbidden > 0 {
+		return fmt.Errorf("restore messages: %w", model.ErrForbidden)
+	}
+
+	if found != len(messageIDs) {
+		return fmt.Errorf("restore messages: %w", model.ErrNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeMessages permanently deletes up to limit messages that expired or, if
+// the respective period is positive, that were sent longer ago than the
+// retention period or moved to the trash longer ago than the trash retention
+// period. Up to limit dead letters that expired or were sent longer ago than
+// the retention period are deleted as well. It returns the number of deleted
+// messages and dead letters.
+func (r *Repository) PurgeMessages(ctx context.Context, retentionPeriod, trashRetentionPeriod time.Duration, limit int) (int64, error) {
+	var purged int64
+	if err := r.pool.QueryRow(ctx, `
+		WITH purged_messages AS (
+			DELETE FROM messages
+			WHERE id IN (
+				SELECT id
+				FROM messages
+				WHERE expires_at <= NOW()
+				OR ($1::interval > INTERVAL '0' AND sent_at < NOW() - $1::interval)
+				OR ($2::interval > INTERVAL '0' AND deleted_at < NOW() - $2::interval)
+				LIMIT $3::integer
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id
+		), purged_dead_letters AS (
+			DELETE FROM dead_letters
+			WHERE id IN (
+				SELECT id
+				FROM dead_letters
+				WHERE expires_at <= NOW()
+				OR ($1::interval > INTERVAL '0' AND sent_at < NOW() - $1::interval)
+				LIMIT $3::integer
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id
+		)
+		SELECT
+			(SELECT COUNT(*) FROM purged_messages) +
+			(SELECT COUNT(*) FROM purged_dead_letters)
+	`, retentionPeriod, trashRetentionPeriod, limit).Scan(&purged); err != nil {
+		return 0, fmt.Errorf("delete messages: %w", err)
+	}
+
+	return purged, nil
+}
+
 func (r *Repository) getSentAt(ctx context.Context, messageID string) (time.Time, error) {
 	var sentAt time.Time
 	if err := r.pool.QueryRow(ctx, `
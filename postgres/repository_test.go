@@ -3,17 +3,30 @@ package postgres_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/RichterMaximilian/osttra-coding-assignment/core"
 	"github.com/RichterMaximilian/osttra-coding-assignment/model"
 	"github.com/RichterMaximilian/osttra-coding-assignment/postgres"
 	"github.com/RichterMaximilian/osttra-coding-assignment/testhelpers"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
-const migrationsPath = "file://../migrations"
+const (
+	migrationsPath  = "file://../migrations"
+	maxReceiveCount = 5
+)
+
+var (
+	ignoreLease          = cmpopts.IgnoreFields(model.Message{}, "FetchedAt", "LeaseExpiresAt")
+	ignoreDeadLetteredAt = cmpopts.IgnoreFields(model.DeadLetter{}, "DeadLetteredAt")
+)
 
 func TestRepository_InsertMessage(t *testing.T) {
 	t.Run("should insert a message", func(t *testing.T) {
@@ -25,20 +38,27 @@ func TestRepository_InsertMessage(t *testing.T) {
 
 		wantMessage := model.Message{
 			ID:                "id",
+			SenderUserName:    "sender",
 			RecipientUserName: "recipient",
 			Content:           "content",
 			SentAt:            time.Now(),
 		}
 
-		if err := r.InsertMessage(ctx, wantMessage); err != nil {
+		gotMessageID, err := r.InsertMessage(ctx, wantMessage, time.Hour)
+		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
+		if got, want := gotMessageID, wantMessage.ID; got != want {
+			t.Errorf("got message ID %q, want %q", got, want)
+		}
+
 		var gotMessage model.Message
 		if err := pool.QueryRow(ctx, `
 			SELECT
 				id,
 				user_name,
+				sender_user_name,
 				content,
 				sent_at,
 				fetched_at
@@ -46,6 +66,7 @@ func TestRepository_InsertMessage(t *testing.T) {
 		`).Scan(
 			&gotMessage.ID,
 			&gotMessage.RecipientUserName,
+			&gotMessage.SenderUserName,
 			&gotMessage.Content,
 			&gotMessage.SentAt,
 			&gotMessage.FetchedAt,
@@ -72,116 +93,187 @@ func TestRepository_InsertMessage(t *testing.T) {
 			SentAt:            time.Now(),
 		}
 
-		if err := r.InsertMessage(ctx, message); err != nil {
+		if _, err := r.InsertMessage(ctx, message, time.Hour); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		err := r.InsertMessage(ctx, message)
+		_, err := r.InsertMessage(ctx, message, time.Hour)
 		if err == nil {
 			t.Fatal("expected error")
 		}
 	})
-}
 
-func TestRepository_GetNewMessages(t *testing.T) {
-	t.Run("should return non-fetched and non-deleted messages and set fetched_at", func(t *testing.T) {
+	t.Run("should return the ID of the message with the same idempotency key", func(t *testing.T) {
 		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
 		defer pool.Close()
 		ctx := context.Background()
 
 		r := postgres.NewRepository(pool.Pool)
 
-		now := time.Now()
-
-		messages := []model.Message{
-			{
-				ID:                "id1",
-				RecipientUserName: "recipient1",
-				Content:           "content1",
-				SentAt:            now.Add(time.Hour),
-				FetchedAt:         nil,
-			},
-			{
-				ID:                "id2",
-				RecipientUserName: "recipient2",
-				Content:           "content2",
-				SentAt:            now,
-				FetchedAt:         &now,
-			},
+		message := model.Message{
+			ID:                "id1",
+			SenderUserName:    "sender",
+			RecipientUserName: "recipient",
+			Content:           "content",
+			SentAt:            time.Now(),
+			IdempotencyKey:    "idempotency-key",
 		}
 
-		for _, message := range messages {
-			insertMessage(ctx, t, pool.Pool, message)
+		if _, err := r.InsertMessage(ctx, message, time.Hour); err != nil {
+			t.Fatalf("unexpected error: %v", err)
 		}
 
-		gotMessages, err := r.GetNewMessages(ctx)
+		retriedMessage := message
+		retriedMessage.ID = "id2"
+
+		gotMessageID, err := r.InsertMessage(ctx, retriedMessage, time.Hour)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		wantMessages := []model.Message{messages[0]}
-
-		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
-			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		if got, want := gotMessageID, message.ID; got != want {
+			t.Errorf("got message ID %q, want %q", got, want)
 		}
 
-		var gotFetchedAt *time.Time
+		var count int
 		if err := pool.QueryRow(ctx, `
-			SELECT fetched_at FROM messages WHERE id = $1::text
-		`, messages[0].ID).Scan(&gotFetchedAt); err != nil {
+			SELECT COUNT(*) FROM messages
+		`).Scan(&count); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if gotFetchedAt == nil {
-			t.Error("fetched at should not be nil")
+		if got, want := count, 1; got != want {
+			t.Errorf("got %d messages, want %d", got, want)
 		}
 	})
 
-	t.Run("should be ordered by sent_at", func(t *testing.T) {
+	t.Run("should insert a message with the same idempotency key of another sender", func(t *testing.T) {
 		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
 		defer pool.Close()
 		ctx := context.Background()
 
 		r := postgres.NewRepository(pool.Pool)
 
-		now := time.Now()
+		message := model.Message{
+			ID:                "id1",
+			SenderUserName:    "sender1",
+			RecipientUserName: "recipient",
+			Content:           "content",
+			SentAt:            time.Now(),
+			IdempotencyKey:    "idempotency-key",
+		}
 
-		messages := []model.Message{
-			{
-				ID:                "id1",
-				RecipientUserName: "recipient1",
-				Content:           "content1",
-				SentAt:            now.Add(time.Hour),
-				FetchedAt:         nil,
-			},
-			{
-				ID:                "id2",
-				RecipientUserName: "recipient2",
-				Content:           "content2",
-				SentAt:            now,
-				FetchedAt:         nil,
-			},
+		if _, err := r.InsertMessage(ctx, message, time.Hour); err != nil {
+			t.Fatalf("unexpected error: %v", err)
 		}
 
-		for _, message := range messages {
-			insertMessage(ctx, t, pool.Pool, message)
+		otherMessage := message
+		otherMessage.ID = "id2"
+		otherMessage.SenderUserName = "sender2"
+
+		gotMessageID, err := r.InsertMessage(ctx, otherMessage, time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotMessageID, otherMessage.ID; got != want {
+			t.Errorf("got message ID %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should insert a message with an idempotency key outside of the idempotency window", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		message := model.Message{
+			ID:                "id1",
+			SenderUserName:    "sender",
+			RecipientUserName: "recipient",
+			Content:           "content",
+			SentAt:            time.Now().Add(-2 * time.Hour),
+			IdempotencyKey:    "idempotency-key",
+		}
+
+		if _, err := r.InsertMessage(ctx, message, time.Hour); err != nil {
+			t.Fatalf("unexpected error: %v", err)
 		}
 
-		gotMessages, err := r.GetNewMessages(ctx)
+		retriedMessage := message
+		retriedMessage.ID = "id2"
+		retriedMessage.SentAt = time.Now()
+
+		gotMessageID, err := r.InsertMessage(ctx, retriedMessage, time.Hour)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		wantMessages := []model.Message{messages[1], messages[0]}
+		if got, want := gotMessageID, retriedMessage.ID; got != want {
+			t.Errorf("got message ID %q, want %q", got, want)
+		}
+	})
+}
 
-		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
-			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+func TestRepository_GetIdempotentMessageID(t *testing.T) {
+	t.Run("should return the message submitted with the idempotency key", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		message := model.Message{
+			ID:                "id1",
+			SenderUserName:    "sender",
+			RecipientUserName: "recipient",
+			Content:           "content1",
+			SentAt:            time.Now(),
+			IdempotencyKey:    "idempotency-key",
+		}
+		if _, err := r.InsertMessage(ctx, message, time.Hour); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotMessageID, err := r.GetIdempotentMessageID(ctx, "sender", "idempotency-key", time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotMessageID, message.ID; got != want {
+			t.Errorf("got message ID %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should return not found if the key was not used within the window", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		message := model.Message{
+			ID:                "id1",
+			SenderUserName:    "sender",
+			RecipientUserName: "recipient",
+			Content:           "content1",
+			SentAt:            time.Now().Add(-2 * time.Hour),
+			IdempotencyKey:    "idempotency-key",
+		}
+		if _, err := r.InsertMessage(ctx, message, time.Hour); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, err := r.GetIdempotentMessageID(ctx, "sender", "idempotency-key", time.Hour)
+		if got, want := err, model.ErrNotFound; !errors.Is(got, want) {
+			t.Errorf("got error %v, want %v", got, want)
 		}
 	})
 }
 
-func TestRepository_DeleteMessages(t *testing.T) {
-	t.Run("should delete messages", func(t *testing.T) {
+func TestRepository_InsertMessages(t *testing.T) {
+	t.Run("should insert all messages", func(t *testing.T) {
 		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
 		defer pool.Close()
 		ctx := context.Background()
@@ -190,46 +282,38 @@ func TestRepository_DeleteMessages(t *testing.T) {
 
 		now := time.Now()
 
-		messages := []model.Message{
+		wantMessages := []model.Message{
 			{
 				ID:                "id1",
+				SenderUserName:    "sender",
 				RecipientUserName: "recipient1",
 				Content:           "content1",
 				SentAt:            now,
-				FetchedAt:         nil,
 			},
 			{
 				ID:                "id2",
+				SenderUserName:    "sender",
 				RecipientUserName: "recipient2",
 				Content:           "content2",
-				SentAt:            now,
-				FetchedAt:         nil,
+				SentAt:            now.Add(time.Second),
 			},
 		}
 
-		messageIDs := make([]string, 0, len(messages))
-		for _, message := range messages {
-			insertMessage(ctx, t, pool.Pool, message)
-			messageIDs = append(messageIDs, message.ID)
-		}
-
-		if err := r.DeleteMessages(ctx, messageIDs); err != nil {
+		if err := r.InsertMessages(ctx, wantMessages); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		var count int
-		if err := pool.QueryRow(ctx, `
-			SELECT COUNT(*) FROM messages
-		`).Scan(&count); err != nil {
+		gotMessages, err := r.GetSentMessages(ctx, "sender")
+		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if got, want := count, 0; got != want {
-			t.Errorf("got %d messages, want %d", got, want)
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
 		}
 	})
 
-	t.Run("should not delete messages if not all message ids are found", func(t *testing.T) {
+	t.Run("should not insert any message if one message ID already exists", func(t *testing.T) {
 		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
 		defer pool.Close()
 		ctx := context.Background()
@@ -238,40 +322,30 @@ func TestRepository_DeleteMessages(t *testing.T) {
 
 		now := time.Now()
 
+		existingMessage := model.Message{
+			ID:                "id2",
+			SenderUserName:    "sender",
+			RecipientUserName: "recipient",
+			Content:           "content",
+			SentAt:            now,
+		}
+		insertMessage(ctx, t, pool.Pool, existingMessage)
+
 		messages := []model.Message{
 			{
 				ID:                "id1",
+				SenderUserName:    "sender",
 				RecipientUserName: "recipient1",
 				Content:           "content1",
 				SentAt:            now,
-				FetchedAt:         nil,
-			},
-			{
-				ID:                "id2",
-				RecipientUserName: "recipient2",
-				Content:           "content2",
-				SentAt:            now,
-				FetchedAt:         nil,
 			},
+			existingMessage,
 		}
 
-		messageIDs := make([]string, 0, len(messages)+1)
-		for _, message := range messages {
-			insertMessage(ctx, t, pool.Pool, message)
-			messageIDs = append(messageIDs, message.ID)
-		}
-
-		messageIDs = append(messageIDs, "id3")
-
-		err := r.DeleteMessages(ctx, messageIDs)
-		if err == nil {
+		if err := r.InsertMessages(ctx, messages); err == nil {
 			t.Fatal("expected error")
 		}
 
-		if got, want := err, model.ErrNotFound; !errors.Is(got, want) {
-			t.Errorf("got error %v, want %v", got, want)
-		}
-
 		var count int
 		if err := pool.QueryRow(ctx, `
 			SELECT COUNT(*) FROM messages
@@ -279,14 +353,14 @@ func TestRepository_DeleteMessages(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if got, want := count, 2; got != want {
+		if got, want := count, 1; got != want {
 			t.Errorf("got %d messages, want %d", got, want)
 		}
 	})
 }
 
-func TestRepository_GetAllMessages(t *testing.T) {
-	t.Run("should return all messages", func(t *testing.T) {
+func TestRepository_GetNewMessages(t *testing.T) {
+	t.Run("should return unacknowledged messages and lease them", func(t *testing.T) {
 		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
 		defer pool.Close()
 		ctx := context.Background()
@@ -298,17 +372,19 @@ func TestRepository_GetAllMessages(t *testing.T) {
 		messages := []model.Message{
 			{
 				ID:                "id1",
-				RecipientUserName: "recipient1",
+				RecipientUserName: "recipient",
 				Content:           "content1",
-				SentAt:            now,
+				SentAt:            now.Add(time.Hour),
 				FetchedAt:         nil,
 			},
 			{
 				ID:                "id2",
-				RecipientUserName: "recipient2",
+				RecipientUserName: "recipient",
 				Content:           "content2",
 				SentAt:            now,
 				FetchedAt:         &now,
+				AcknowledgedAt:    &now,
+				ReceiveCount:      1,
 			},
 		}
 
@@ -316,14 +392,33 @@ func TestRepository_GetAllMessages(t *testing.T) {
 			insertMessage(ctx, t, pool.Pool, message)
 		}
 
-		gotMessages, err := r.GetAllMessages(ctx, nil, nil)
+		gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, maxReceiveCount)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if diff := cmp.Diff(messages, gotMessages); diff != "" {
+		wantMessage := messages[0]
+		wantMessage.ReceiveCount = 1
+		wantMessages := []model.Message{wantMessage}
+
+		if diff := cmp.Diff(wantMessages, gotMessages, ignoreLease); diff != "" {
 			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
 		}
+
+		var gotFetchedAt, gotLeaseExpiresAt *time.Time
+		if err := pool.QueryRow(ctx, `
+			SELECT fetched_at, lease_expires_at FROM messages WHERE id = $1::text
+		`, messages[0].ID).Scan(&gotFetchedAt, &gotLeaseExpiresAt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if gotFetchedAt == nil {
+			t.Error("fetched at should not be nil")
+		}
+
+		if gotLeaseExpiresAt == nil || !gotLeaseExpiresAt.After(*gotFetchedAt) {
+			t.Errorf("got lease expires at %v, want after fetched at %v", gotLeaseExpiresAt, gotFetchedAt)
+		}
 	})
 
 	t.Run("should be ordered by sent_at", func(t *testing.T) {
@@ -338,14 +433,14 @@ func TestRepository_GetAllMessages(t *testing.T) {
 		messages := []model.Message{
 			{
 				ID:                "id1",
-				RecipientUserName: "recipient1",
+				RecipientUserName: "recipient",
 				Content:           "content1",
 				SentAt:            now.Add(time.Hour),
 				FetchedAt:         nil,
 			},
 			{
 				ID:                "id2",
-				RecipientUserName: "recipient2",
+				RecipientUserName: "recipient",
 				Content:           "content2",
 				SentAt:            now,
 				FetchedAt:         nil,
@@ -356,19 +451,22 @@ func TestRepository_GetAllMessages(t *testing.T) {
 			insertMessage(ctx, t, pool.Pool, message)
 		}
 
-		gotMessages, err := r.GetAllMessages(ctx, nil, nil)
+		gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, maxReceiveCount)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
 		wantMessages := []model.Message{messages[1], messages[0]}
+		for i := range wantMessages {
+			wantMessages[i].ReceiveCount = 1
+		}
 
-		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+		if diff := cmp.Diff(wantMessages, gotMessages, ignoreLease); diff != "" {
 			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
 		}
 	})
 
-	t.Run("should return messages later than startCursor", func(t *testing.T) {
+	t.Run("should only return messages of the recipient", func(t *testing.T) {
 		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
 		defer pool.Close()
 		ctx := context.Background()
@@ -382,7 +480,7 @@ func TestRepository_GetAllMessages(t *testing.T) {
 				ID:                "id1",
 				RecipientUserName: "recipient1",
 				Content:           "content1",
-				SentAt:            now.Add(time.Hour),
+				SentAt:            now,
 				FetchedAt:         nil,
 			},
 			{
@@ -392,32 +490,38 @@ func TestRepository_GetAllMessages(t *testing.T) {
 				SentAt:            now,
 				FetchedAt:         nil,
 			},
-			{
-				ID:                "id3",
-				RecipientUserName: "recipient3",
-				Content:           "content3",
-				SentAt:            now.Add(2 * time.Hour),
-				FetchedAt:         nil,
-			},
 		}
 
 		for _, message := range messages {
 			insertMessage(ctx, t, pool.Pool, message)
 		}
 
-		gotMessages, err := r.GetAllMessages(ctx, &messages[0].ID, nil)
+		gotMessages, err := r.GetNewMessages(ctx, "recipient1", time.Minute, maxReceiveCount)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		wantMessages := []model.Message{messages[0], messages[2]}
+		wantMessage := messages[0]
+		wantMessage.ReceiveCount = 1
+		wantMessages := []model.Message{wantMessage}
 
-		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+		if diff := cmp.Diff(wantMessages, gotMessages, ignoreLease); diff != "" {
 			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
 		}
+
+		var gotFetchedAt *time.Time
+		if err := pool.QueryRow(ctx, `
+			SELECT fetched_at FROM messages WHERE id = $1::text
+		`, messages[1].ID).Scan(&gotFetchedAt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if gotFetchedAt != nil {
+			t.Error("fetched at of other recipient should be nil")
+		}
 	})
 
-	t.Run("should return messages earlier than endCursor", func(t *testing.T) {
+	t.Run("should not return a message to more than one concurrent caller", func(t *testing.T) {
 		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
 		defer pool.Close()
 		ctx := context.Background()
@@ -426,75 +530,2085 @@ func TestRepository_GetAllMessages(t *testing.T) {
 
 		now := time.Now()
 
-		messages := []model.Message{
-			{
-				ID:                "id1",
-				RecipientUserName: "recipient1",
-				Content:           "content1",
-				SentAt:            now.Add(time.Hour),
-				FetchedAt:         nil,
-			},
-			{
-				ID:                "id2",
-				RecipientUserName: "recipient2",
-				Content:           "content2",
-				SentAt:            now,
-				FetchedAt:         nil,
-			},
-			{
-				ID:                "id3",
-				RecipientUserName: "recipient3",
-				Content:           "content3",
-				SentAt:            now.Add(2 * time.Hour),
-				FetchedAt:         nil,
-			},
+		const messageCount = 100
+		for i := 0; i < messageCount; i++ {
+			insertMessage(ctx, t, pool.Pool, model.Message{
+				ID:                fmt.Sprintf("id%d", i),
+				RecipientUserName: "recipient",
+				Content:           fmt.Sprintf("content%d", i),
+				SentAt:            now.Add(time.Duration(i) * time.Millisecond),
+			})
 		}
 
-		for _, message := range messages {
-			insertMessage(ctx, t, pool.Pool, message)
+		const callerCount = 10
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			gotCounts = make(map[string]int)
+		)
+		for i := 0; i < callerCount; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, maxReceiveCount)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				for _, message := range gotMessages {
+					gotCounts[message.ID]++
+				}
+			}()
 		}
+		wg.Wait()
 
-		gotMessages, err := r.GetAllMessages(ctx, nil, &messages[0].ID)
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+		for id, count := range gotCounts {
+			if count > 1 {
+				t.Errorf("got message %q %d times, want at most once", id, count)
+			}
 		}
 
-		wantMessages := []model.Message{messages[1], messages[0]}
-
-		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
-			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		if got, want := len(gotCounts), messageCount; got != want {
+			t.Errorf("got %d distinct messages, want %d", got, want)
 		}
 	})
 
-	t.Run("should return error if startCursor is not found", func(t *testing.T) {
+	t.Run("should not return messages with an active lease", func(t *testing.T) {
 		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
 		defer pool.Close()
 		ctx := context.Background()
 
 		r := postgres.NewRepository(pool.Pool)
 
-		invalidID := "invalidID"
+		now := time.Now()
+		leaseExpiresAt := now.Add(time.Hour)
 
-		_, err := r.GetAllMessages(ctx, &invalidID, nil)
+		insertMessage(ctx, t, pool.Pool, model.Message{
+			ID:                "id1",
+			RecipientUserName: "recipient",
+			Content:           "content1",
+			SentAt:            now,
+			FetchedAt:         &now,
+			LeaseExpiresAt:    &leaseExpiresAt,
+			ReceiveCount:      1,
+		})
+
+		gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, maxReceiveCount)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := len(gotMessages), 0; got != want {
+			t.Errorf("got %d messages, want %d", got, want)
+		}
+	})
+
+	t.Run("should return messages again after their lease expired", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		leaseExpiresAt := now.Add(-time.Minute)
+
+		message := model.Message{
+			ID:                "id1",
+			RecipientUserName: "recipient",
+			Content:           "content1",
+			SentAt:            now,
+			FetchedAt:         &now,
+			LeaseExpiresAt:    &leaseExpiresAt,
+			ReceiveCount:      1,
+		}
+		insertMessage(ctx, t, pool.Pool, message)
+
+		gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, maxReceiveCount)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantMessage := message
+		wantMessage.ReceiveCount = 2
+		wantMessages := []model.Message{wantMessage}
+
+		if diff := cmp.Diff(wantMessages, gotMessages, ignoreLease); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should not return messages scheduled for later delivery", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            now,
+				DeliverAt:         &past,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient",
+				Content:           "content2",
+				SentAt:            now,
+				DeliverAt:         &future,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, maxReceiveCount)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantMessage := messages[0]
+		wantMessage.ReceiveCount = 1
+		wantMessages := []model.Message{wantMessage}
+
+		if diff := cmp.Diff(wantMessages, gotMessages, ignoreLease); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should not return expired messages", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            now,
+				ExpiresAt:         &future,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient",
+				Content:           "content2",
+				SentAt:            now,
+				ExpiresAt:         &past,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, maxReceiveCount)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantMessage := messages[0]
+		wantMessage.ReceiveCount = 1
+		wantMessages := []model.Message{wantMessage}
+
+		if diff := cmp.Diff(wantMessages, gotMessages, ignoreLease); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should move messages exceeding the max receive count to dead letters", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		leaseExpiresAt := now.Add(-time.Minute)
+		expiresAt := now.Add(time.Hour)
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            now,
+				DeliverAt:         &now,
+				ExpiresAt:         &expiresAt,
+				FetchedAt:         &now,
+				LeaseExpiresAt:    &leaseExpiresAt,
+				ReceiveCount:      maxReceiveCount,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient",
+				Content:           "content2",
+				SentAt:            now,
+				FetchedAt:         &now,
+				LeaseExpiresAt:    &leaseExpiresAt,
+				ReceiveCount:      maxReceiveCount - 1,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, maxReceiveCount)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantMessage := messages[1]
+		wantMessage.ReceiveCount = maxReceiveCount
+		wantMessages := []model.Message{wantMessage}
+
+		if diff := cmp.Diff(wantMessages, gotMessages, ignoreLease); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+
+		gotDeadLetters, err := r.GetDeadLetters(ctx, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantDeadLetters := []model.DeadLetter{{Message: messages[0]}}
+
+		if diff := cmp.Diff(wantDeadLetters, gotDeadLetters, ignoreLease, ignoreDeadLetteredAt); diff != "" {
+			t.Fatalf("dead letters mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestRepository_AcknowledgeMessages(t *testing.T) {
+	t.Run("should acknowledge fetched messages", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		leaseExpiresAt := now.Add(time.Minute)
+
+		message := model.Message{
+			ID:                "id1",
+			RecipientUserName: "recipient",
+			Content:           "content1",
+			SentAt:            now,
+			FetchedAt:         &now,
+			LeaseExpiresAt:    &leaseExpiresAt,
+			ReceiveCount:      1,
+		}
+		insertMessage(ctx, t, pool.Pool, message)
+
+		if err := r.AcknowledgeMessages(ctx, nil, []string{message.ID}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var gotAcknowledgedAt, gotLeaseExpiresAt *time.Time
+		if err := pool.QueryRow(ctx, `
+			SELECT acknowledged_at, lease_expires_at FROM messages WHERE id = $1::text
+		`, message.ID).Scan(&gotAcknowledgedAt, &gotLeaseExpiresAt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if gotAcknowledgedAt == nil {
+			t.Error("acknowledged at should not be nil")
+		}
+
+		if gotLeaseExpiresAt != nil {
+			t.Errorf("got lease expires at %v, want nil", gotLeaseExpiresAt)
+		}
+	})
+
+	t.Run("should acknowledge messages whose IDs are repeated", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now, FetchedAt: &now})
+
+		if err := r.AcknowledgeMessages(ctx, nil, []string{"id1", "id1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var gotAcknowledgedAt *time.Time
+		if err := pool.QueryRow(ctx, `
+			SELECT acknowledged_at FROM messages WHERE id = $1::text
+		`, "id1").Scan(&gotAcknowledgedAt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if gotAcknowledgedAt == nil {
+			t.Error("acknowledged at should not be nil")
+		}
+	})
+
+	t.Run("should not acknowledge messages if not all message ids are fetched", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            now,
+				FetchedAt:         &now,
+				ReceiveCount:      1,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient",
+				Content:           "content2",
+				SentAt:            now,
+				FetchedAt:         nil,
+			},
+		}
+
+		messageIDs := make([]string, 0, len(messages))
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+			messageIDs = append(messageIDs, message.ID)
+		}
+
+		err := r.AcknowledgeMessages(ctx, nil, messageIDs)
 		if err == nil {
 			t.Fatal("expected error")
 		}
 
-		if got, want := err, model.ErrNotFound; !errors.Is(got, want) {
+		if got, want := err, model.ErrNotFound; !errors.Is(got, want) {
+			t.Errorf("got error %v, want %v", got, want)
+		}
+
+		var count int
+		if err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages WHERE acknowledged_at IS NOT NULL
+		`).Scan(&count); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := count, 0; got != want {
+			t.Errorf("got %d acknowledged messages, want %d", got, want)
+		}
+	})
+
+	t.Run("should not acknowledge any message if one belongs to another recipient", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now, FetchedAt: &now})
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id2", RecipientUserName: "other-recipient", Content: "content2", SentAt: now, FetchedAt: &now})
+
+		recipient := "recipient"
+		err := r.AcknowledgeMessages(ctx, &recipient, []string{"id1", "id2"})
+		if got, want := err, model.ErrForbidden; !errors.Is(got, want) {
+			t.Errorf("got error %v, want %v", got, want)
+		}
+
+		var count int
+		if err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages WHERE acknowledged_at IS NOT NULL
+		`).Scan(&count); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := count, 0; got != want {
+			t.Errorf("got %d acknowledged messages, want %d", got, want)
+		}
+	})
+}
+
+func TestRepository_MarkMessagesUnfetched(t *testing.T) {
+	t.Run("should return acknowledged messages as new messages again", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		message := model.Message{
+			ID:                "id1",
+			RecipientUserName: "recipient",
+			Content:           "content1",
+			SentAt:            now,
+			FetchedAt:         &now,
+			AcknowledgedAt:    &now,
+			ReceiveCount:      3,
+		}
+		insertMessage(ctx, t, pool.Pool, message)
+
+		if err := r.MarkMessagesUnfetched(ctx, nil, []string{message.ID}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := len(gotMessages), 1; got != want {
+			t.Fatalf("got %d new messages, want %d", got, want)
+		}
+
+		if got, want := gotMessages[0].ReceiveCount, 1; got != want {
+			t.Errorf("got receive count %d, want %d", got, want)
+		}
+	})
+
+	t.Run("should mark messages unfetched whose IDs are repeated", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now, FetchedAt: &now})
+
+		if err := r.MarkMessagesUnfetched(ctx, nil, []string{"id1", "id1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := len(gotMessages), 1; got != want {
+			t.Fatalf("got %d new messages, want %d", got, want)
+		}
+	})
+
+	t.Run("should return not found if a message does not exist", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now, FetchedAt: &now})
+
+		err := r.MarkMessagesUnfetched(ctx, nil, []string{"id1", "id2"})
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Fatalf("got error %v, want %v", err, model.ErrNotFound)
+		}
+
+		var gotFetchedAt *time.Time
+		if err := pool.QueryRow(ctx, `
+			SELECT fetched_at FROM messages WHERE id = 'id1'
+		`).Scan(&gotFetchedAt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if gotFetchedAt == nil {
+			t.Error("fetched at should not be nil")
+		}
+	})
+}
+
+func TestRepository_CancelScheduledMessage(t *testing.T) {
+	t.Run("should delete a scheduled message", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		deliverAt := now.Add(time.Hour)
+
+		message := model.Message{
+			ID:                "id1",
+			RecipientUserName: "recipient",
+			Content:           "content1",
+			SentAt:            now,
+			DeliverAt:         &deliverAt,
+		}
+		insertMessage(ctx, t, pool.Pool, message)
+
+		if err := r.CancelScheduledMessage(ctx, nil, message.ID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var count int
+		if err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages
+		`).Scan(&count); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := count, 0; got != want {
+			t.Errorf("got %d messages, want %d", got, want)
+		}
+	})
+
+	t.Run("should return conflict if the message is already delivered", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		message := model.Message{
+			ID:                "id1",
+			RecipientUserName: "recipient",
+			Content:           "content1",
+			SentAt:            time.Now(),
+		}
+		insertMessage(ctx, t, pool.Pool, message)
+
+		err := r.CancelScheduledMessage(ctx, nil, message.ID)
+		if got, want := err, model.ErrConflict; !errors.Is(got, want) {
+			t.Errorf("got error %v, want %v", got, want)
+		}
+
+		var count int
+		if err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages
+		`).Scan(&count); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := count, 1; got != want {
+			t.Errorf("got %d messages, want %d", got, want)
+		}
+	})
+
+	t.Run("should return not found if the message does not exist", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		err := r.CancelScheduledMessage(ctx, nil, "id1")
+		if got, want := err, model.ErrNotFound; !errors.Is(got, want) {
+			t.Errorf("got error %v, want %v", got, want)
+		}
+	})
+	t.Run("should not delete a message sent by another sender", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		deliverAt := now.Add(time.Hour)
+
+		message := model.Message{
+			ID:                "id1",
+			SenderUserName:    "other-sender",
+			RecipientUserName: "recipient",
+			Content:           "content1",
+			SentAt:            now,
+			DeliverAt:         &deliverAt,
+		}
+		insertMessage(ctx, t, pool.Pool, message)
+
+		sender := "sender"
+		err := r.CancelScheduledMessage(ctx, &sender, message.ID)
+		if got, want := err, model.ErrForbidden; !errors.Is(got, want) {
+			t.Errorf("got error %v, want %v", got, want)
+		}
+
+		var count int
+		if err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages
+		`).Scan(&count); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := count, 1; got != want {
+			t.Errorf("got %d messages, want %d", got, want)
+		}
+	})
+}
+
+func TestRepository_DeleteMessages(t *testing.T) {
+	t.Run("should move messages to the trash", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient1",
+				Content:           "content1",
+				SentAt:            now,
+				FetchedAt:         nil,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient2",
+				Content:           "content2",
+				SentAt:            now,
+				FetchedAt:         nil,
+			},
+		}
+
+		messageIDs := make([]string, 0, len(messages))
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+			messageIDs = append(messageIDs, message.ID)
+		}
+
+		if err := r.DeleteMessages(ctx, nil, messageIDs); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var count int
+		if err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages WHERE deleted_at IS NULL
+		`).Scan(&count); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := count, 0; got != want {
+			t.Errorf("got %d messages, want %d", got, want)
+		}
+	})
+
+	t.Run("should not delete messages if not all message ids are found", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient1",
+				Content:           "content1",
+				SentAt:            now,
+				FetchedAt:         nil,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient2",
+				Content:           "content2",
+				SentAt:            now,
+				FetchedAt:         nil,
+			},
+		}
+
+		messageIDs := make([]string, 0, len(messages)+1)
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+			messageIDs = append(messageIDs, message.ID)
+		}
+
+		messageIDs = append(messageIDs, "id3")
+
+		err := r.DeleteMessages(ctx, nil, messageIDs)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		if got, want := err, model.ErrNotFound; !errors.Is(got, want) {
+			t.Errorf("got error %v, want %v", got, want)
+		}
+
+		var missingErr *model.MissingMessagesError
+		if !errors.As(err, &missingErr) {
+			t.Fatalf("got error %v, want %T", err, missingErr)
+		}
+
+		if diff := cmp.Diff([]string{"id3"}, missingErr.MessageIDs); diff != "" {
+			t.Errorf("missing message IDs mismatch (-want +got):\n%s", diff)
+		}
+
+		var count int
+		if err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages WHERE deleted_at IS NULL
+		`).Scan(&count); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := count, 2; got != want {
+			t.Errorf("got %d messages, want %d", got, want)
+		}
+	})
+
+	t.Run("should not delete any message if one belongs to another recipient", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now})
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id2", RecipientUserName: "other-recipient", Content: "content2", SentAt: now})
+
+		recipient := "recipient"
+		err := r.DeleteMessages(ctx, &recipient, []string{"id1", "id2"})
+		if got, want := err, model.ErrForbidden; !errors.Is(got, want) {
+			t.Errorf("got error %v, want %v", got, want)
+		}
+
+		var count int
+		if err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages WHERE deleted_at IS NULL
+		`).Scan(&count); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := count, 2; got != want {
+			t.Errorf("got %d messages, want %d", got, want)
+		}
+	})
+}
+
+func TestRepository_DeleteExistingMessages(t *testing.T) {
+	t.Run("should move existing messages to the trash and report missing ones", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now},
+			{ID: "id2", RecipientUserName: "recipient", Content: "content2", SentAt: now},
+			{ID: "id4", RecipientUserName: "recipient", Content: "content4", SentAt: now},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotResult, err := r.DeleteExistingMessages(ctx, nil, []string{"id2", "id3", "id1", "id2"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantResult := model.DeleteResult{Deleted: []string{"id2", "id1"}, Missing: []string{"id3"}}
+		if diff := cmp.Diff(wantResult, gotResult); diff != "" {
+			t.Errorf("result mismatch (-want +got):\n%s", diff)
+		}
+
+		var count int
+		if err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages WHERE deleted_at IS NULL
+		`).Scan(&count); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := count, 1; got != want {
+			t.Errorf("got %d messages, want %d", got, want)
+		}
+	})
+}
+
+func TestRepository_GetMessage(t *testing.T) {
+	t.Run("should return the message", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				SenderUserName:    "sender",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            now,
+				FetchedAt:         &now,
+			},
+			{
+				ID:                "id2",
+				SenderUserName:    "sender",
+				RecipientUserName: "recipient",
+				Content:           "content2",
+				SentAt:            now,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotMessage, err := r.GetMessage(ctx, "id1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(messages[0], gotMessage); diff != "" {
+			t.Fatalf("message mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return not found if the message does not exist", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		_, err := r.GetMessage(ctx, "id")
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Fatalf("got error %v, want %v", err, model.ErrNotFound)
+		}
+	})
+}
+
+func TestRepository_GetAllMessages(t *testing.T) {
+	t.Run("should return all messages", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient1",
+				Content:           "content1",
+				SentAt:            now,
+				FetchedAt:         nil,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient2",
+				Content:           "content2",
+				SentAt:            now,
+				FetchedAt:         &now,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotPage, err := r.GetAllMessages(ctx, core.MessageFilter{}, model.PageRequest{Limit: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		gotMessages := gotPage.Messages
+
+		if diff := cmp.Diff(messages, gotMessages); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should be ordered by sent_at", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient1",
+				Content:           "content1",
+				SentAt:            now.Add(time.Hour),
+				FetchedAt:         nil,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient2",
+				Content:           "content2",
+				SentAt:            now,
+				FetchedAt:         nil,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotPage, err := r.GetAllMessages(ctx, core.MessageFilter{}, model.PageRequest{Limit: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		gotMessages := gotPage.Messages
+
+		wantMessages := []model.Message{messages[1], messages[0]}
+
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return messages later than startCursor", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient1",
+				Content:           "content1",
+				SentAt:            now.Add(time.Hour),
+				FetchedAt:         nil,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient2",
+				Content:           "content2",
+				SentAt:            now,
+				FetchedAt:         nil,
+			},
+			{
+				ID:                "id3",
+				RecipientUserName: "recipient3",
+				Content:           "content3",
+				SentAt:            now.Add(2 * time.Hour),
+				FetchedAt:         nil,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotPage, err := r.GetAllMessages(ctx, core.MessageFilter{StartCursor: &messages[0].ID}, model.PageRequest{Limit: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		gotMessages := gotPage.Messages
+
+		wantMessages := []model.Message{messages[0], messages[2]}
+
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return messages earlier than endCursor", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient1",
+				Content:           "content1",
+				SentAt:            now.Add(time.Hour),
+				FetchedAt:         nil,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient2",
+				Content:           "content2",
+				SentAt:            now,
+				FetchedAt:         nil,
+			},
+			{
+				ID:                "id3",
+				RecipientUserName: "recipient3",
+				Content:           "content3",
+				SentAt:            now.Add(2 * time.Hour),
+				FetchedAt:         nil,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotPage, err := r.GetAllMessages(ctx, core.MessageFilter{EndCursor: &messages[0].ID}, model.PageRequest{Limit: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		gotMessages := gotPage.Messages
+
+		wantMessages := []model.Message{messages[1], messages[0]}
+
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should not return messages scheduled for later delivery", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		deliverAt := now.Add(time.Hour)
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient1",
+				Content:           "content1",
+				SentAt:            now,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient2",
+				Content:           "content2",
+				SentAt:            now,
+				DeliverAt:         &deliverAt,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotPage, err := r.GetAllMessages(ctx, core.MessageFilter{}, model.PageRequest{Limit: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		gotMessages := gotPage.Messages
+
+		wantMessages := []model.Message{messages[0]}
+
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should page through messages sent at the same time in both directions", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now().Truncate(time.Microsecond)
+
+		var messages []model.Message
+		for _, id := range []string{"id1", "id2", "id3", "id4", "id5"} {
+			message := model.Message{
+				ID:                id,
+				RecipientUserName: "recipient",
+				Content:           "content",
+				SentAt:            now,
+			}
+			insertMessage(ctx, t, pool.Pool, message)
+			messages = append(messages, message)
+		}
+
+		firstPage, err := r.GetAllMessages(ctx, core.MessageFilter{}, model.PageRequest{Limit: 2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(messages[0:2], firstPage.Messages); diff != "" {
+			t.Fatalf("first page mismatch (-want +got):\n%s", diff)
+		}
+		if firstPage.PrevCursor != nil {
+			t.Errorf("got prev cursor %+v on first page, want nil", firstPage.PrevCursor)
+		}
+
+		secondPage, err := r.GetAllMessages(ctx, core.MessageFilter{}, model.PageRequest{Limit: 2, Cursor: firstPage.NextCursor})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(messages[2:4], secondPage.Messages); diff != "" {
+			t.Fatalf("second page mismatch (-want +got):\n%s", diff)
+		}
+
+		lastPage, err := r.GetAllMessages(ctx, core.MessageFilter{}, model.PageRequest{Limit: 2, Cursor: secondPage.NextCursor})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(messages[4:], lastPage.Messages); diff != "" {
+			t.Fatalf("last page mismatch (-want +got):\n%s", diff)
+		}
+		if lastPage.NextCursor != nil {
+			t.Errorf("got next cursor %+v on last page, want nil", lastPage.NextCursor)
+		}
+
+		prevPage, err := r.GetAllMessages(ctx, core.MessageFilter{}, model.PageRequest{Limit: 2, Cursor: lastPage.PrevCursor})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(messages[2:4], prevPage.Messages); diff != "" {
+			t.Fatalf("previous page mismatch (-want +got):\n%s", diff)
+		}
+
+		firstPageAgain, err := r.GetAllMessages(ctx, core.MessageFilter{}, model.PageRequest{Limit: 2, Cursor: prevPage.PrevCursor})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(messages[0:2], firstPageAgain.Messages); diff != "" {
+			t.Fatalf("first page mismatch (-want +got):\n%s", diff)
+		}
+		if firstPageAgain.PrevCursor != nil {
+			t.Errorf("got prev cursor %+v on first page, want nil", firstPageAgain.PrevCursor)
+		}
+	})
+
+	t.Run("should return messages matching the filter", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		earlier, later := now.Add(-time.Hour), now.Add(time.Hour)
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "Hello 100% World",
+				SentAt:            now,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient",
+				Content:           "Hello 100% World",
+				SentAt:            now,
+				FetchedAt:         &now,
+			},
+			{
+				ID:                "id3",
+				RecipientUserName: "other-recipient",
+				Content:           "Hello 100% World",
+				SentAt:            now,
+			},
+			{
+				ID:                "id4",
+				RecipientUserName: "recipient",
+				Content:           "Hello 100 World",
+				SentAt:            now,
+			},
+			{
+				ID:                "id5",
+				RecipientUserName: "recipient",
+				Content:           "Hello 100% World",
+				SentAt:            earlier.Add(-time.Minute),
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		recipientUserName, fetched, content := "recipient", false, "100% world"
+		gotPage, err := r.GetAllMessages(ctx, core.MessageFilter{
+			RecipientUserName: &recipientUserName,
+			Fetched:           &fetched,
+			SentAfter:         &earlier,
+			SentBefore:        &later,
+			ContentContains:   &content,
+		}, model.PageRequest{Limit: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff([]model.Message{messages[0]}, gotPage.Messages); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return error if startCursor is not found", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		invalidID := "invalidID"
+
+		_, err := r.GetAllMessages(ctx, core.MessageFilter{StartCursor: &invalidID}, model.PageRequest{Limit: 10})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		if got, want := err, model.ErrNotFound; !errors.Is(got, want) {
+			t.Errorf("got error %v, want %v", got, want)
+		}
+	})
+
+	t.Run("should return error if endCursor is not found", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		invalidID := "invalidID"
+
+		_, err := r.GetAllMessages(ctx, core.MessageFilter{EndCursor: &invalidID}, model.PageRequest{Limit: 10})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		if got, want := err, model.ErrNotFound; !errors.Is(got, want) {
+			t.Errorf("got error %v, want %v", got, want)
+		}
+	})
+}
+
+func TestRepository_SearchMessages(t *testing.T) {
+	t.Run("should return matching messages ordered by rank with snippets", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "The invoice is attached",
+				SentAt:            now,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient",
+				Content:           "Invoices, invoices and more invoices",
+				SentAt:            now,
+			},
+			{
+				ID:                "id3",
+				RecipientUserName: "recipient",
+				Content:           "Lunch at noon?",
+				SentAt:            now,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotPage, err := r.SearchMessages(ctx, nil, "invoice", 10, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var gotIDs []string
+		for _, result := range gotPage.Results {
+			gotIDs = append(gotIDs, result.ID)
+		}
+
+		if diff := cmp.Diff([]string{"id2", "id1"}, gotIDs); diff != "" {
+			t.Fatalf("results mismatch (-want +got):\n%s", diff)
+		}
+
+		if got, want := gotPage.Results[1].Snippet, "The <mark>invoice</mark> is attached"; got != want {
+			t.Errorf("got snippet %q, want %q", got, want)
+		}
+
+		if gotPage.NextOffset != nil || gotPage.PrevOffset != nil {
+			t.Errorf("got offsets %v and %v, want none", gotPage.NextOffset, gotPage.PrevOffset)
+		}
+	})
+
+	t.Run("should escape the content of snippets", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		insertMessage(ctx, t, pool.Pool, model.Message{
+			ID:                "id1",
+			RecipientUserName: "recipient",
+			Content:           `<img src="x" onerror="alert(1)"> invoice`,
+			SentAt:            time.Now(),
+		})
+
+		gotPage, err := r.SearchMessages(ctx, nil, "invoice", 10, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := len(gotPage.Results), 1; got != want {
+			t.Fatalf("got %d results, want %d", got, want)
+		}
+
+		snippet := gotPage.Results[0].Snippet
+		if strings.Contains(snippet, "<img") {
+			t.Errorf("got unescaped snippet %q", snippet)
+		}
+		if !strings.Contains(snippet, "&lt;img") || !strings.Contains(snippet, "<mark>invoice</mark>") {
+			t.Errorf("got snippet %q, want escaped content with highlighted matches", snippet)
+		}
+	})
+
+	t.Run("should page through results", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		for _, id := range []string{"id1", "id2", "id3"} {
+			insertMessage(ctx, t, pool.Pool, model.Message{
+				ID:                id,
+				RecipientUserName: "recipient",
+				Content:           "invoice",
+				SentAt:            time.Now(),
+			})
+		}
+
+		firstPage, err := r.SearchMessages(ctx, nil, "invoice", 2, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := len(firstPage.Results), 2; got != want {
+			t.Fatalf("got %d results, want %d", got, want)
+		}
+		if firstPage.NextOffset == nil || *firstPage.NextOffset != 2 {
+			t.Fatalf("got next offset %v, want 2", firstPage.NextOffset)
+		}
+
+		secondPage, err := r.SearchMessages(ctx, nil, "invoice", 2, *firstPage.NextOffset)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := len(secondPage.Results), 1; got != want {
+			t.Fatalf("got %d results, want %d", got, want)
+		}
+		if secondPage.NextOffset != nil {
+			t.Errorf("got next offset %d, want nil", *secondPage.NextOffset)
+		}
+		if secondPage.PrevOffset == nil || *secondPage.PrevOffset != 0 {
+			t.Errorf("got previous offset %v, want 0", secondPage.PrevOffset)
+		}
+	})
+}
+
+func TestRepository_GetSentMessages(t *testing.T) {
+	t.Run("should return messages of the sender ordered by sent_at", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				SenderUserName:    "sender1",
+				RecipientUserName: "recipient1",
+				Content:           "content1",
+				SentAt:            now.Add(time.Hour),
+			},
+			{
+				ID:                "id2",
+				SenderUserName:    "sender1",
+				RecipientUserName: "recipient2",
+				Content:           "content2",
+				SentAt:            now,
+				FetchedAt:         &now,
+			},
+			{
+				ID:                "id3",
+				SenderUserName:    "sender2",
+				RecipientUserName: "recipient1",
+				Content:           "content3",
+				SentAt:            now,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotMessages, err := r.GetSentMessages(ctx, "sender1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantMessages := []model.Message{messages[1], messages[0]}
+
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestRepository_GetReceivedMessages(t *testing.T) {
+	t.Run("should return delivered messages of the recipient after the cursor", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		earlier, later := now.Add(-time.Hour), now.Add(time.Hour)
+		deliveredAt := now.Add(-time.Minute)
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            earlier,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient",
+				Content:           "content2",
+				SentAt:            now.Add(-2 * time.Minute),
+				FetchedAt:         &now,
+			},
+			{
+				ID:                "id3",
+				RecipientUserName: "recipient",
+				Content:           "content3",
+				SentAt:            earlier,
+				DeliverAt:         &deliveredAt,
+			},
+			{
+				ID:                "id4",
+				RecipientUserName: "recipient",
+				Content:           "content4",
+				SentAt:            now,
+				DeliverAt:         &later,
+			},
+			{
+				ID:                "id5",
+				RecipientUserName: "other-recipient",
+				Content:           "content5",
+				SentAt:            now,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		cursor := "id1"
+		gotMessages, err := r.GetReceivedMessages(ctx, "recipient", &cursor, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantMessages := []model.Message{messages[1], messages[2]}
+
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should not return more messages than the limit", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            now.Add(-time.Minute),
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient",
+				Content:           "content2",
+				SentAt:            now,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotMessages, err := r.GetReceivedMessages(ctx, "recipient", nil, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantMessages := []model.Message{messages[0]}
+
+		if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should return error if cursor is not found", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		cursor := "id"
+		_, err := r.GetReceivedMessages(ctx, "recipient", &cursor, 10)
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Fatalf("got error %v, want %v", err, model.ErrNotFound)
+		}
+	})
+}
+
+func TestRepository_PurgeMessages(t *testing.T) {
+	t.Run("should delete expired messages and messages older than the retention period", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+		messages := []model.Message{
+			{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            now,
+				ExpiresAt:         &past,
+			},
+			{
+				ID:                "id2",
+				RecipientUserName: "recipient",
+				Content:           "content2",
+				SentAt:            now.Add(-48 * time.Hour),
+			},
+			{
+				ID:                "id3",
+				RecipientUserName: "recipient",
+				Content:           "content3",
+				SentAt:            now,
+				ExpiresAt:         &future,
+			},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotPurged, err := r.PurgeMessages(ctx, 24*time.Hour, 0, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotPurged, int64(2); got != want {
+			t.Errorf("got %d purged messages, want %d", got, want)
+		}
+
+		var ids []string
+		rows, err := pool.Query(ctx, `SELECT id FROM messages`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ids = append(ids, id)
+		}
+
+		if diff := cmp.Diff([]string{"id3"}, ids); diff != "" {
+			t.Errorf("remaining messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should not delete more messages than the limit", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		past := time.Now().Add(-time.Minute)
+
+		for _, id := range []string{"id1", "id2", "id3"} {
+			insertMessage(ctx, t, pool.Pool, model.Message{
+				ID:                id,
+				RecipientUserName: "recipient",
+				Content:           "content",
+				SentAt:            past,
+				ExpiresAt:         &past,
+			})
+		}
+
+		gotPurged, err := r.PurgeMessages(ctx, 0, 0, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotPurged, int64(2); got != want {
+			t.Errorf("got %d purged messages, want %d", got, want)
+		}
+	})
+
+	t.Run("should delete expired dead letters and dead letters older than the retention period", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		past := now.Add(-time.Minute)
+
+		deadLetters := []model.DeadLetter{
+			{
+				Message: model.Message{
+					ID:                "id1",
+					RecipientUserName: "recipient",
+					Content:           "content1",
+					SentAt:            now,
+					ExpiresAt:         &past,
+				},
+				DeadLetteredAt: now,
+			},
+			{
+				Message: model.Message{
+					ID:                "id2",
+					RecipientUserName: "recipient",
+					Content:           "content2",
+					SentAt:            now.Add(-48 * time.Hour),
+				},
+				DeadLetteredAt: now,
+			},
+			{
+				Message: model.Message{
+					ID:                "id3",
+					RecipientUserName: "recipient",
+					Content:           "content3",
+					SentAt:            now,
+				},
+				DeadLetteredAt: now,
+			},
+		}
+
+		for _, deadLetter := range deadLetters {
+			insertDeadLetter(ctx, t, pool.Pool, deadLetter)
+		}
+
+		gotPurged, err := r.PurgeMessages(ctx, 24*time.Hour, 0, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotPurged, int64(2); got != want {
+			t.Errorf("got %d purged dead letters, want %d", got, want)
+		}
+
+		var id string
+		if err := pool.QueryRow(ctx, `SELECT id FROM dead_letters`).Scan(&id); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := id, "id3"; got != want {
+			t.Errorf("got remaining dead letter %q, want %q", got, want)
+		}
+	})
+}
+
+func TestRepository_PurgeMessages_Trash(t *testing.T) {
+	t.Run("should delete messages that have been in the trash longer than the trash retention period", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+		longAgo, recently := now.Add(-48*time.Hour), now.Add(-time.Minute)
+
+		messages := []model.Message{
+			{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: longAgo, DeletedAt: &longAgo},
+			{ID: "id2", RecipientUserName: "recipient", Content: "content2", SentAt: longAgo, DeletedAt: &recently},
+			{ID: "id3", RecipientUserName: "recipient", Content: "content3", SentAt: longAgo},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		gotPurged, err := r.PurgeMessages(ctx, 0, 24*time.Hour, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := gotPurged, int64(1); got != want {
+			t.Errorf("got %d purged messages, want %d", got, want)
+		}
+
+		var count int
+		if err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages WHERE id = 'id1'
+		`).Scan(&count); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := count, 0; got != want {
+			t.Errorf("got %d messages with ID id1, want %d", got, want)
+		}
+	})
+}
+
+func TestRepository_GetTrash(t *testing.T) {
+	t.Run("should return deleted messages of the recipient", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		earlier := now.Add(-time.Hour)
+
+		messages := []model.Message{
+			{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: earlier, DeletedAt: &earlier},
+			{ID: "id2", RecipientUserName: "recipient", Content: "content2", SentAt: earlier, DeletedAt: &now},
+			{ID: "id3", RecipientUserName: "recipient", Content: "content3", SentAt: earlier},
+			{ID: "id4", RecipientUserName: "other-recipient", Content: "content4", SentAt: earlier, DeletedAt: &now},
+		}
+
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		recipient := "recipient"
+		gotMessages, err := r.GetTrash(ctx, &recipient)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantMessages := []model.Message{messages[1], messages[0]}
+		if diff := cmp.Diff(wantMessages, gotMessages, cmpopts.EquateApproxTime(time.Millisecond)); diff != "" {
+			t.Errorf("messages mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestRepository_RestoreMessages(t *testing.T) {
+	t.Run("should restore messages from the trash", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now, DeletedAt: &now})
+
+		if err := r.RestoreMessages(ctx, nil, []string{"id1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := r.GetMessage(ctx, "id1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should restore messages whose IDs are repeated", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now, DeletedAt: &now})
+
+		if err := r.RestoreMessages(ctx, nil, []string{"id1", "id1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := r.GetMessage(ctx, "id1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should not restore messages if not all are in the trash", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now, DeletedAt: &now})
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id2", RecipientUserName: "recipient", Content: "content2", SentAt: now})
+
+		err := r.RestoreMessages(ctx, nil, []string{"id1", "id2"})
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Fatalf("got error %v, want %v", err, model.ErrNotFound)
+		}
+
+		if _, err := r.GetMessage(ctx, "id1"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("got error %v, want %v", err, model.ErrNotFound)
+		}
+	})
+
+	t.Run("should not restore any message if one belongs to another recipient", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id1", RecipientUserName: "recipient", Content: "content1", SentAt: now, DeletedAt: &now})
+		insertMessage(ctx, t, pool.Pool, model.Message{ID: "id2", RecipientUserName: "other-recipient", Content: "content2", SentAt: now, DeletedAt: &now})
+
+		recipient := "recipient"
+		err := r.RestoreMessages(ctx, &recipient, []string{"id1", "id2"})
+		if got, want := err, model.ErrForbidden; !errors.Is(got, want) {
 			t.Errorf("got error %v, want %v", got, want)
 		}
+
+		if _, err := r.GetMessage(ctx, "id1"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("got error %v, want %v", err, model.ErrNotFound)
+		}
 	})
+}
 
-	t.Run("should return error if endCursor is not found", func(t *testing.T) {
+func TestRepository_GetDeadLetters(t *testing.T) {
+	t.Run("should return dead letters of the recipient", func(t *testing.T) {
 		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
 		defer pool.Close()
 		ctx := context.Background()
 
 		r := postgres.NewRepository(pool.Pool)
 
-		invalidID := "invalidID"
+		now := time.Now()
+
+		deadLetters := []model.DeadLetter{
+			{
+				Message: model.Message{
+					ID:                "id1",
+					RecipientUserName: "recipient1",
+					Content:           "content1",
+					SentAt:            now,
+					FetchedAt:         &now,
+					ReceiveCount:      maxReceiveCount,
+				},
+				DeadLetteredAt: now,
+			},
+			{
+				Message: model.Message{
+					ID:                "id2",
+					RecipientUserName: "recipient2",
+					Content:           "content2",
+					SentAt:            now,
+					FetchedAt:         &now,
+					ReceiveCount:      maxReceiveCount,
+				},
+				DeadLetteredAt: now,
+			},
+		}
+
+		for _, deadLetter := range deadLetters {
+			insertDeadLetter(ctx, t, pool.Pool, deadLetter)
+		}
+
+		recipientUserName := "recipient1"
+		gotDeadLetters, err := r.GetDeadLetters(ctx, &recipientUserName)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantDeadLetters := []model.DeadLetter{deadLetters[0]}
+
+		if diff := cmp.Diff(wantDeadLetters, gotDeadLetters); diff != "" {
+			t.Fatalf("dead letters mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestRepository_RedriveDeadLetters(t *testing.T) {
+	t.Run("should move dead letters back to messages", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		expiresAt := now.Add(time.Hour)
+
+		deadLetter := model.DeadLetter{
+			Message: model.Message{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            now,
+				DeliverAt:         &now,
+				ExpiresAt:         &expiresAt,
+				FetchedAt:         &now,
+				ReceiveCount:      maxReceiveCount,
+			},
+			DeadLetteredAt: now,
+		}
+		insertDeadLetter(ctx, t, pool.Pool, deadLetter)
+
+		if err := r.RedriveDeadLetters(ctx, []string{deadLetter.ID}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotMessages, err := r.GetNewMessages(ctx, "recipient", time.Minute, maxReceiveCount)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wantMessage := deadLetter.Message
+		wantMessage.ReceiveCount = 1
+		wantMessages := []model.Message{wantMessage}
+
+		if diff := cmp.Diff(wantMessages, gotMessages, ignoreLease); diff != "" {
+			t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+		}
+
+		gotDeadLetters, err := r.GetDeadLetters(ctx, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := len(gotDeadLetters), 0; got != want {
+			t.Errorf("got %d dead letters, want %d", got, want)
+		}
+	})
+
+	t.Run("should redrive dead letters whose IDs are repeated", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
+
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		deadLetter := model.DeadLetter{
+			Message: model.Message{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            now,
+				ReceiveCount:      maxReceiveCount,
+			},
+			DeadLetteredAt: now,
+		}
+		insertDeadLetter(ctx, t, pool.Pool, deadLetter)
+
+		if err := r.RedriveDeadLetters(ctx, []string{deadLetter.ID, deadLetter.ID}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotDeadLetters, err := r.GetDeadLetters(ctx, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := len(gotDeadLetters), 0; got != want {
+			t.Errorf("got %d dead letters, want %d", got, want)
+		}
+	})
+
+	t.Run("should not redrive dead letters if not all message ids are found", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx := context.Background()
 
-		_, err := r.GetAllMessages(ctx, nil, &invalidID)
+		r := postgres.NewRepository(pool.Pool)
+
+		now := time.Now()
+
+		deadLetter := model.DeadLetter{
+			Message: model.Message{
+				ID:                "id1",
+				RecipientUserName: "recipient",
+				Content:           "content1",
+				SentAt:            now,
+				ReceiveCount:      maxReceiveCount,
+			},
+			DeadLetteredAt: now,
+		}
+		insertDeadLetter(ctx, t, pool.Pool, deadLetter)
+
+		err := r.RedriveDeadLetters(ctx, []string{deadLetter.ID, "id2"})
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -502,6 +2616,15 @@ func TestRepository_GetAllMessages(t *testing.T) {
 		if got, want := err, model.ErrNotFound; !errors.Is(got, want) {
 			t.Errorf("got error %v, want %v", got, want)
 		}
+
+		gotDeadLetters, err := r.GetDeadLetters(ctx, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := len(gotDeadLetters), 1; got != want {
+			t.Errorf("got %d dead letters, want %d", got, want)
+		}
 	})
 }
 
@@ -510,22 +2633,84 @@ func insertMessage(ctx context.Context, t *testing.T, pool *pgxpool.Pool, messag
 		INSERT INTO messages (
 			id,
 			user_name,
+			sender_user_name,
 			content,
 			sent_at,
-			fetched_at
+			deliver_at,
+			expires_at,
+			fetched_at,
+			lease_expires_at,
+			acknowledged_at,
+			deleted_at,
+			receive_count
 		) VALUES (
 			$1::text,
 			$2::text,
 			$3::text,
-			$4::timestamptz,
-			$5::timestamptz
+			$4::text,
+			$5::timestamptz,
+			$6::timestamptz,
+			$7::timestamptz,
+			$8::timestamptz,
+			$9::timestamptz,
+			$10::timestamptz,
+			$11::timestamptz,
+			$12::integer
 		)
 	`,
 		message.ID,
 		message.RecipientUserName,
+		message.SenderUserName,
 		message.Content,
 		message.SentAt,
+		message.DeliverAt,
+		message.ExpiresAt,
 		message.FetchedAt,
+		message.LeaseExpiresAt,
+		message.AcknowledgedAt,
+		message.DeletedAt,
+		message.ReceiveCount,
+	); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func insertDeadLetter(ctx context.Context, t *testing.T, pool *pgxpool.Pool, deadLetter model.DeadLetter) {
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO dead_letters (
+			id,
+			user_name,
+			sender_user_name,
+			content,
+			sent_at,
+			deliver_at,
+			expires_at,
+			fetched_at,
+			receive_count,
+			dead_lettered_at
+		) VALUES (
+			$1::text,
+			$2::text,
+			$3::text,
+			$4::text,
+			$5::timestamptz,
+			$6::timestamptz,
+			$7::timestamptz,
+			$8::timestamptz,
+			$9::integer,
+			$10::timestamptz
+		)
+	`,
+		deadLetter.ID,
+		deadLetter.RecipientUserName,
+		deadLetter.SenderUserName,
+		deadLetter.Content,
+		deadLetter.SentAt,
+		deadLetter.DeliverAt,
+		deadLetter.ExpiresAt,
+		deadLetter.FetchedAt,
+		deadLetter.ReceiveCount,
+		deadLetter.DeadLetteredAt,
 	); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+)
+
+// messagesInsertedChannel is the channel the trigger of the messages table
+// notifies on.
+const messagesInsertedChannel = "messages_inserted"
+
+// DefaultSubscriptionBufferSize is the number of events buffered for each
+// subscription before further events are dropped.
+const DefaultSubscriptionBufferSize = 64
+
+const (
+	minReconnectDelay = 100 * time.Millisecond
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Subscriber listens for the notifications sent whenever a message is
+// inserted and passes them on to in-process subscriptions of the recipient.
+//
+// Slow subscriptions do not hold up the others: once the buffer of a
+// subscription is full, further events for it are dropped. The buffered events
+// still wake the subscription up, so it learns about the dropped messages by
+// looking for new messages as it does after every event.
+type Subscriber struct {
+	connString string
+	logger     *zap.Logger
+	bufferSize int
+
+	mu            sync.Mutex
+	subscriptions map[string]map[chan model.MessageEvent]struct{}
+}
+
+type subscriberOptsFunc func(s *Subscriber)
+
+// NewSubscriber creates a subscriber holding its own connection to the
+// database at connString, as a listening connection cannot be shared.
+func NewSubscriber(connString string, logger *zap.Logger, opts ...subscriberOptsFunc) *Subscriber {
+	s := &Subscriber{
+		connString:    connString,
+		logger:        logger,
+		bufferSize:    DefaultSubscriptionBufferSize,
+		subscriptions: make(map[string]map[chan model.MessageEvent]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func SubscriberBufferSize(bufferSize int) subscriberOptsFunc {
+	return func(s *Subscriber) {
+		s.bufferSize = bufferSize
+	}
+}
+
+// Subscribe returns a channel receiving the events of messages inserted for
+// the recipient until the context is canceled.
+func (s *Subscriber) Subscribe(ctx context.Context, recipientUserName string) <-chan model.MessageEvent {
+	ch := make(chan model.MessageEvent, s.bufferSize)
+
+	s.mu.Lock()
+	if s.subscriptions[recipientUserName] == nil {
+		s.subscriptions[recipientUserName] = make(map[chan model.MessageEvent]struct{})
+	}
+	s.subscriptions[recipientUserName][ch] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		delete(s.subscriptions[recipientUserName], ch)
+		if len(s.subscriptions[recipientUserName]) == 0 {
+			delete(s.subscriptions, recipientUserName)
+		}
+		s.mu.Unlock()
+	}()
+
+	return ch
+}
+
+// Run listens for notifications until the context is canceled. If the
+// connection fails, it reconnects with an increasing delay.
+func (s *Subscriber) Run(ctx context.Context) {
+	delay := minReconnectDelay
+	for {
+		err := s.listen(ctx, func() { delay = minReconnectDelay })
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.logger.Error("failed to listen for inserted messages", zap.Error(err), zap.Duration("reconnect_in", delay))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+func (s *Subscriber) listen(ctx context.Context, listening func()) error {
+	conn, err := pgx.Connect(ctx, s.connString)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{messagesInsertedChannel}.Sanitize()); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	listening()
+
+	// Notifications sent while no connection was listening are lost, so every
+	// subscription is told to look for new messages.
+	s.publishAll()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+
+		var event model.MessageEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			s.logger.Error("failed to decode notification", zap.Error(err), zap.String("payload", notification.Payload))
+			continue
+		}
+
+		s.publish(event)
+	}
+}
+
+func (s *Subscriber) publish(event model.MessageEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subscriptions[event.RecipientUserName] {
+		send(ch, event)
+	}
+}
+
+func (s *Subscriber) publishAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for recipientUserName, chs := range s.subscriptions {
+		for ch := range chs {
+			send(ch, model.MessageEvent{RecipientUserName: recipientUserName})
+		}
+	}
+}
+
+func send(ch chan model.MessageEvent, event model.MessageEvent) {
+	select {
+	case ch <- event:
+	default:
+	}
+}
@@ -0,0 +1,147 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RichterMaximilian/osttra-coding-assignment/model"
+	"github.com/RichterMaximilian/osttra-coding-assignment/postgres"
+	"github.com/RichterMaximilian/osttra-coding-assignment/testhelpers"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestSubscriber(t *testing.T) {
+	t.Run("should publish inserted messages to subscriptions of the recipient", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		s := postgres.NewSubscriber(pool.Config().ConnString(), zap.NewNop())
+
+		events := s.Subscribe(ctx, "recipient")
+		otherEvents := s.Subscribe(ctx, "other-recipient")
+
+		go s.Run(ctx)
+
+		// Once listening, the subscriber tells every subscription to look for
+		// messages it may have missed.
+		if got, want := receiveEvent(t, events), (model.MessageEvent{RecipientUserName: "recipient"}); got != want {
+			t.Fatalf("got event %+v, want %+v", got, want)
+		}
+		receiveEvent(t, otherEvents)
+
+		insertMessage(ctx, t, pool.Pool, model.Message{
+			ID:                "id",
+			RecipientUserName: "recipient",
+			Content:           "content",
+			SentAt:            time.Now(),
+		})
+
+		wantEvent := model.MessageEvent{MessageID: "id", RecipientUserName: "recipient"}
+		if diff := cmp.Diff(wantEvent, receiveEvent(t, events)); diff != "" {
+			t.Errorf("event mismatch (-want +got):\n%s", diff)
+		}
+
+		select {
+		case event := <-otherEvents:
+			t.Errorf("got event %+v for other recipient", event)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("should reconnect after the connection failed", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		s := postgres.NewSubscriber(pool.Config().ConnString(), zap.NewNop())
+
+		events := s.Subscribe(ctx, "recipient")
+
+		go s.Run(ctx)
+
+		receiveEvent(t, events)
+
+		if _, err := pool.Exec(ctx, `
+			SELECT pg_terminate_backend(pid)
+			FROM pg_stat_activity
+			WHERE query LIKE 'LISTEN%'
+			AND pid <> pg_backend_pid()
+		`); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, want := receiveEvent(t, events), (model.MessageEvent{RecipientUserName: "recipient"}); got != want {
+			t.Fatalf("got event %+v, want %+v", got, want)
+		}
+
+		insertMessage(ctx, t, pool.Pool, model.Message{
+			ID:                "id",
+			RecipientUserName: "recipient",
+			Content:           "content",
+			SentAt:            time.Now(),
+		})
+
+		wantEvent := model.MessageEvent{MessageID: "id", RecipientUserName: "recipient"}
+		if diff := cmp.Diff(wantEvent, receiveEvent(t, events)); diff != "" {
+			t.Errorf("event mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("should drop events of a subscription with a full buffer", func(t *testing.T) {
+		pool := testhelpers.GetMigratedDBPool(context.Background(), migrationsPath)
+		defer pool.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		s := postgres.NewSubscriber(pool.Config().ConnString(), zap.NewNop(), postgres.SubscriberBufferSize(1))
+
+		events := s.Subscribe(ctx, "recipient")
+		otherEvents := s.Subscribe(ctx, "other-recipient")
+
+		go s.Run(ctx)
+
+		receiveEvent(t, events)
+		receiveEvent(t, otherEvents)
+
+		messages := []model.Message{
+			{ID: "id1", RecipientUserName: "recipient", Content: "content", SentAt: time.Now()},
+			{ID: "id2", RecipientUserName: "recipient", Content: "content", SentAt: time.Now()},
+			{ID: "id3", RecipientUserName: "other-recipient", Content: "content", SentAt: time.Now()},
+		}
+		for _, message := range messages {
+			insertMessage(ctx, t, pool.Pool, message)
+		}
+
+		// Notifications are published in order, so the event of the other
+		// recipient arrives after both events of the recipient were handled.
+		receiveEvent(t, otherEvents)
+
+		wantEvent := model.MessageEvent{MessageID: "id1", RecipientUserName: "recipient"}
+		if diff := cmp.Diff(wantEvent, receiveEvent(t, events)); diff != "" {
+			t.Errorf("event mismatch (-want +got):\n%s", diff)
+		}
+
+		select {
+		case event := <-events:
+			t.Errorf("got unexpected event %+v", event)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+func receiveEvent(t *testing.T, events <-chan model.MessageEvent) model.MessageEvent {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("got no event")
+		return model.MessageEvent{}
+	}
+}